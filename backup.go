@@ -0,0 +1,28 @@
+package l5db
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Backup writes a consistent snapshot of the database to w.
+// The written bytes are a database file, a directory containing them in
+// a file named db can be opened with Open.
+// Backup writes the state committed when it was called from a snapshot,
+// write transactions can be committed while the backup is being written.
+func (d *DB) Backup(w io.Writer) error {
+	s, err := d.st.Snapshot()
+	if err != nil {
+		return errors.Wrap(err, "while creating snapshot for backup")
+	}
+
+	defer s.Close()
+
+	_, err = s.WriteTo(w)
+	if err != nil {
+		return errors.Wrap(err, "while writing backup")
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package l5db_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/draganm/l5db"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackup(t *testing.T) {
+	td, cleanup := createTempDir(t)
+	defer cleanup()
+
+	backupDir, cleanupBackup := createTempDir(t)
+	defer cleanupBackup()
+
+	db, err := l5db.Open(td)
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.CreateMap("m")
+	require.NoError(t, err)
+
+	err = db.Put("m/abc", []byte{1, 2, 3})
+	require.NoError(t, err)
+
+	tx, err := db.NewWriteTransaction(context.Background())
+	require.NoError(t, err)
+
+	err = tx.Put("m/def", []byte{4, 5, 6})
+	require.NoError(t, err)
+
+	f, err := os.Create(filepath.Join(backupDir, "db"))
+	require.NoError(t, err)
+
+	err = db.Backup(f)
+	require.NoError(t, err)
+
+	err = f.Close()
+	require.NoError(t, err)
+
+	err = tx.Commit()
+	require.NoError(t, err)
+
+	bdb, err := l5db.Open(backupDir)
+	require.NoError(t, err)
+	defer bdb.Close()
+
+	err = bdb.Verify()
+	require.NoError(t, err)
+
+	d, err := bdb.Get("m/abc")
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, d)
+
+	ex, err := bdb.Exists("m/def")
+	require.NoError(t, err)
+	require.False(t, ex)
+
+	require.Equal(t, db.LastTxID()-1, bdb.LastTxID())
+}
+
+// blockingWriter signals the first write and waits until it is released
+// before writing.
+type blockingWriter struct {
+	w        io.Writer
+	started  chan struct{}
+	release  chan struct{}
+	signaled bool
+}
+
+func (b *blockingWriter) Write(p []byte) (int, error) {
+	if !b.signaled {
+		b.signaled = true
+		close(b.started)
+		<-b.release
+	}
+	return b.w.Write(p)
+}
+
+func TestCommitDuringBackup(t *testing.T) {
+	td, cleanup := createTempDir(t)
+	defer cleanup()
+
+	backupDir, cleanupBackup := createTempDir(t)
+	defer cleanupBackup()
+
+	db, err := l5db.Open(td)
+	require.NoError(t, err)
+	defer db.Close()
+
+	large := bytes.Repeat([]byte{1, 2, 3}, 100000)
+
+	err = db.Put("large", large)
+	require.NoError(t, err)
+
+	err = db.Put("abc", []byte{1, 2, 3})
+	require.NoError(t, err)
+
+	f, err := os.Create(filepath.Join(backupDir, "db"))
+	require.NoError(t, err)
+
+	bw := &blockingWriter{
+		w:       f,
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+
+	backupDone := make(chan error, 1)
+	go func() {
+		backupDone <- db.Backup(bw)
+	}()
+
+	<-bw.started
+
+	// overwrite the blocks of the backed up state while the backup is running
+	err = db.Delete("large")
+	require.NoError(t, err)
+
+	err = db.Put("abc", []byte{4, 5, 6})
+	require.NoError(t, err)
+
+	err = db.Put("def", bytes.Repeat([]byte{7}, 200000))
+	require.NoError(t, err)
+
+	close(bw.release)
+
+	require.NoError(t, <-backupDone)
+
+	err = f.Close()
+	require.NoError(t, err)
+
+	bdb, err := l5db.Open(backupDir)
+	require.NoError(t, err)
+	defer bdb.Close()
+
+	err = bdb.Verify()
+	require.NoError(t, err)
+
+	d, err := bdb.Get("large")
+	require.NoError(t, err)
+	require.Equal(t, large, d)
+
+	d, err = bdb.Get("abc")
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, d)
+
+	ex, err := bdb.Exists("def")
+	require.NoError(t, err)
+	require.False(t, ex)
+}
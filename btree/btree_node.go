@@ -17,8 +17,15 @@ type structure struct {
 type btreeNode interface {
 	put(key []byte, value store.Address) (store.Address, bool, error)
 	get(key []byte) (store.Address, error)
+	delete(key []byte) (store.Address, error)
 	isFull() bool
+	keyCount() int
 	split() (kv, store.Address, store.Address, error)
+	min() (kv, error)
+	max() (kv, error)
+	content() (kvs, children)
+	setContent(kvs, children) (store.Address, error)
+	free(freeValue func(store.Address) error) error
 	structure() structure
 }
 
@@ -1,8 +1,12 @@
 package btree_test
 
 import (
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"math/rand"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/draganm/l5db/btree"
@@ -33,6 +37,18 @@ func createTestStore(t *testing.T) (*store.Store, func()) {
 
 }
 
+func requireValidBTree(t *testing.T, m store.Memory, a store.Address) {
+	visited := map[store.Address]bool{}
+	err := btree.Verify(m, a, func(ba store.Address) error {
+		require.False(t, visited[ba], "block %d visited twice", ba)
+		visited[ba] = true
+		return nil
+	}, func(key []byte, value store.Address) error {
+		return nil
+	})
+	require.NoError(t, err)
+}
+
 func TestCreateEmptyBTRee(t *testing.T) {
 	ts, cleanup := createTestStore(t)
 	defer cleanup()
@@ -180,3 +196,362 @@ func TestSplittingFullInternalNode(t *testing.T) {
 	})
 
 }
+
+func TestDeleteFromLeaf(t *testing.T) {
+	ts, cleanup := createTestStore(t)
+	defer cleanup()
+
+	a, err := btree.CreateEmptyBTree(ts, 2, 32)
+	require.NoError(t, err)
+
+	err = btree.Put(ts, a, []byte{1, 2, 3}, store.Address(666))
+	require.NoError(t, err)
+
+	err = btree.Delete(ts, a, []byte{1, 2, 3})
+	require.NoError(t, err)
+
+	_, err = btree.Get(ts, a, []byte{1, 2, 3})
+	require.Equal(t, btree.ErrNotFound, err)
+
+	cnt, err := btree.Count(ts, a)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), cnt)
+
+	err = btree.Delete(ts, a, []byte{1, 2, 3})
+	require.Equal(t, btree.ErrNotFound, err)
+}
+
+func TestDeleteWithRebalancing(t *testing.T) {
+	for _, order := range []byte{2, 3, 5} {
+		t.Run(fmt.Sprintf("t=%d", order), func(t *testing.T) {
+			ts, cleanup := createTestStore(t)
+			defer cleanup()
+
+			a, err := btree.CreateEmptyBTree(ts, order, 32)
+			require.NoError(t, err)
+
+			rnd := rand.New(rand.NewSource(42))
+
+			keys := [][]byte{}
+			for i := 0; i < 300; i++ {
+				keys = append(keys, []byte{byte(i >> 8), byte(i)})
+			}
+
+			for _, i := range rnd.Perm(len(keys)) {
+				err = btree.Put(ts, a, keys[i], store.Address(i+1))
+				require.NoError(t, err)
+			}
+
+			requireValidBTree(t, ts, a)
+
+			deleted := map[int]bool{}
+
+			for n, i := range rnd.Perm(len(keys)) {
+				err = btree.Delete(ts, a, keys[i])
+				require.NoError(t, err)
+				deleted[i] = true
+
+				cnt, err := btree.Count(ts, a)
+				require.NoError(t, err)
+				require.Equal(t, uint64(len(keys)-n-1), cnt)
+
+				if n%10 != 0 {
+					continue
+				}
+
+				requireValidBTree(t, ts, a)
+
+				for j, k := range keys {
+					v, err := btree.Get(ts, a, k)
+					if deleted[j] {
+						require.Equal(t, btree.ErrNotFound, err)
+						continue
+					}
+					require.NoError(t, err)
+					require.Equal(t, store.Address(j+1), v)
+				}
+			}
+
+		})
+	}
+}
+
+func TestCursor(t *testing.T) {
+	for _, order := range []byte{2, 3, 5} {
+		t.Run(fmt.Sprintf("t=%d", order), func(t *testing.T) {
+			ts, cleanup := createTestStore(t)
+			defer cleanup()
+
+			a, err := btree.CreateEmptyBTree(ts, order, 32)
+			require.NoError(t, err)
+
+			t.Run("empty tree", func(t *testing.T) {
+				c, err := btree.NewCursor(ts, a)
+				require.NoError(t, err)
+
+				err = c.First()
+				require.NoError(t, err)
+				require.False(t, c.Valid())
+
+				err = c.Last()
+				require.NoError(t, err)
+				require.False(t, c.Valid())
+
+				err = c.Seek([]byte{1})
+				require.NoError(t, err)
+				require.False(t, c.Valid())
+			})
+
+			rnd := rand.New(rand.NewSource(42))
+
+			// even keys only, so that seeking odd keys can be tested
+			keys := [][]byte{}
+			for i := 0; i < 300; i += 2 {
+				keys = append(keys, []byte{byte(i >> 8), byte(i)})
+			}
+
+			for _, i := range rnd.Perm(len(keys)) {
+				err = btree.Put(ts, a, keys[i], store.Address(i+1))
+				require.NoError(t, err)
+			}
+
+			c, err := btree.NewCursor(ts, a)
+			require.NoError(t, err)
+
+			t.Run("forward iteration", func(t *testing.T) {
+				i := 0
+				for err = c.First(); c.Valid(); err = c.Next() {
+					require.NoError(t, err)
+					require.Equal(t, keys[i], c.Key())
+					require.Equal(t, store.Address(i+1), c.Value())
+					i++
+				}
+				require.NoError(t, err)
+				require.Equal(t, len(keys), i)
+			})
+
+			t.Run("backward iteration", func(t *testing.T) {
+				i := len(keys) - 1
+				for err = c.Last(); c.Valid(); err = c.Prev() {
+					require.NoError(t, err)
+					require.Equal(t, keys[i], c.Key())
+					i--
+				}
+				require.NoError(t, err)
+				require.Equal(t, -1, i)
+			})
+
+			t.Run("seek", func(t *testing.T) {
+				for i := 0; i < 300; i++ {
+					err = c.Seek([]byte{byte(i >> 8), byte(i)})
+					require.NoError(t, err)
+
+					expected := (i + 1) / 2
+					if expected == len(keys) {
+						require.False(t, c.Valid())
+						continue
+					}
+
+					require.True(t, c.Valid())
+					require.Equal(t, keys[expected], c.Key())
+
+					if expected > 0 {
+						err = c.Prev()
+						require.NoError(t, err)
+						require.True(t, c.Valid())
+						require.Equal(t, keys[expected-1], c.Key())
+
+						err = c.Next()
+						require.NoError(t, err)
+						require.True(t, c.Valid())
+						require.Equal(t, keys[expected], c.Key())
+					}
+				}
+			})
+
+		})
+	}
+}
+
+func TestScan(t *testing.T) {
+	ts, cleanup := createTestStore(t)
+	defer cleanup()
+
+	a, err := btree.CreateEmptyBTree(ts, 2, 32)
+	require.NoError(t, err)
+
+	keys := []string{"a1", "a2", "b", "b1", "b2", "b\xff", "b\xff\xff", "c", "c1", "d"}
+
+	for i, k := range keys {
+		err = btree.Put(ts, a, []byte(k), store.Address(i+1))
+		require.NoError(t, err)
+	}
+
+	scan := func(r btree.Range) []string {
+		res := []string{}
+		err := btree.Scan(ts, a, r, func(key []byte, value store.Address) error {
+			res = append(res, string(key))
+			return nil
+		})
+		require.NoError(t, err)
+		return res
+	}
+
+	cases := []struct {
+		title    string
+		r        btree.Range
+		expected []string
+	}{
+		{
+			title:    "all",
+			r:        btree.Range{},
+			expected: keys,
+		},
+		{
+			title:    "start and end",
+			r:        btree.Range{Start: []byte("a2"), End: []byte("c")},
+			expected: []string{"a2", "b", "b1", "b2", "b\xff", "b\xff\xff"},
+		},
+		{
+			title:    "start and end, not existing keys",
+			r:        btree.Range{Start: []byte("a3"), End: []byte("b3")},
+			expected: []string{"b", "b1", "b2"},
+		},
+		{
+			title:    "reverse",
+			r:        btree.Range{Start: []byte("a2"), End: []byte("b2"), Reverse: true},
+			expected: []string{"b1", "b", "a2"},
+		},
+		{
+			title:    "reverse without end",
+			r:        btree.Range{Start: []byte("c1"), Reverse: true},
+			expected: []string{"d", "c1"},
+		},
+		{
+			title:    "limit",
+			r:        btree.Range{Start: []byte("b"), Limit: 2},
+			expected: []string{"b", "b1"},
+		},
+		{
+			title:    "reverse with limit",
+			r:        btree.Range{End: []byte("b"), Limit: 1, Reverse: true},
+			expected: []string{"a2"},
+		},
+		{
+			title:    "prefix",
+			r:        btree.PrefixRange([]byte("b")),
+			expected: []string{"b", "b1", "b2", "b\xff", "b\xff\xff"},
+		},
+		{
+			title:    "prefix ending with 0xff",
+			r:        btree.PrefixRange([]byte("b\xff")),
+			expected: []string{"b\xff", "b\xff\xff"},
+		},
+		{
+			title:    "empty range",
+			r:        btree.Range{Start: []byte("e")},
+			expected: []string{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.title, func(t *testing.T) {
+			require.Equal(t, c.expected, scan(c.r))
+		})
+	}
+
+}
+
+func TestKeysLargerThanKeySizeHint(t *testing.T) {
+	for _, order := range []byte{2, 3} {
+		t.Run(fmt.Sprintf("t=%d", order), func(t *testing.T) {
+			ts, cleanup := createTestStore(t)
+			defer cleanup()
+
+			a, err := btree.CreateEmptyBTree(ts, order, 8)
+			require.NoError(t, err)
+
+			rnd := rand.New(rand.NewSource(42))
+
+			keys := [][]byte{}
+			for i := 0; i < 100; i++ {
+				k := make([]byte, 1+rnd.Intn(2000))
+				rnd.Read(k)
+				keys = append(keys, k)
+			}
+
+			for i, k := range keys {
+				err = btree.Put(ts, a, k, store.Address(i+1))
+				require.NoError(t, err)
+			}
+
+			for i, k := range keys {
+				v, err := btree.Get(ts, a, k)
+				require.NoError(t, err)
+				require.Equal(t, store.Address(i+1), v)
+			}
+
+			for _, i := range rnd.Perm(len(keys)) {
+				err = btree.Delete(ts, a, keys[i])
+				require.NoError(t, err)
+			}
+
+			cnt, err := btree.Count(ts, a)
+			require.NoError(t, err)
+			require.Equal(t, uint64(0), cnt)
+		})
+	}
+
+	t.Run("max key length", func(t *testing.T) {
+		ts, cleanup := createTestStore(t)
+		defer cleanup()
+
+		a, err := btree.CreateEmptyBTree(ts, 2, 32)
+		require.NoError(t, err)
+
+		k := make([]byte, 65535)
+		k[0] = 1
+
+		err = btree.Put(ts, a, k, store.Address(1))
+		require.NoError(t, err)
+
+		v, err := btree.Get(ts, a, k)
+		require.NoError(t, err)
+		require.Equal(t, store.Address(1), v)
+
+		err = btree.Put(ts, a, make([]byte, 65536), store.Address(2))
+		require.Error(t, err)
+	})
+}
+
+func TestTruncatedMetaBlockIsCorrupt(t *testing.T) {
+	td, cleanup := tempDir(t)
+	defer cleanup()
+
+	st, err := store.Open(td, 1024*1024)
+	require.NoError(t, err)
+
+	a, err := btree.CreateEmptyBTree(st, 2, 8)
+	require.NoError(t, err)
+
+	err = st.Close()
+	require.NoError(t, err)
+
+	// change the size class of the meta block to the smallest one
+	f, err := os.OpenFile(filepath.Join(td, "db"), os.O_RDWR, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte{4}, int64(a.UInt64()-6))
+	require.NoError(t, err)
+	err = f.Close()
+	require.NoError(t, err)
+
+	st, err = store.Open(td, 1024*1024)
+	require.NoError(t, err)
+	defer st.Close()
+
+	_, err = btree.Count(st, a)
+	require.True(t, errors.Is(err, store.ErrCorrupt))
+
+	_, err = btree.Get(st, a, []byte("abc"))
+	require.True(t, errors.Is(err, store.ErrCorrupt))
+}
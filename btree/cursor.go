@@ -0,0 +1,222 @@
+package btree
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/draganm/l5db/store"
+	"github.com/pkg/errors"
+)
+
+// Cursor iterates over the key/values of a btree in key order.
+// A cursor is only valid as long as the btree is not modified.
+type Cursor struct {
+	m     store.Memory
+	met   meta
+	stack []cursorFrame
+}
+
+// cursorFrame is one node on the path from the root to the current
+// position.
+// For the node at the top of the stack idx is the index of the current
+// key/value, for all other nodes it is the index of the child the cursor
+// descended into.
+type cursorFrame struct {
+	kvs      kvs
+	children children
+	idx      int
+}
+
+func (f cursorFrame) isLeaf() bool {
+	return f.children == nil
+}
+
+func NewCursor(m store.Memory, a store.Address) (*Cursor, error) {
+	met, err := getMetaNode(m, a)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cursor{
+		m:   m,
+		met: met,
+	}, nil
+}
+
+func (c *Cursor) loadFrame(a store.Address) (cursorFrame, error) {
+	n, err := getNode(c.m, a, c.met.t(), c.met.keySizeHint())
+	if err != nil {
+		return cursorFrame{}, errors.Wrap(err, "while loading node")
+	}
+
+	k, ch := n.content()
+
+	return cursorFrame{
+		kvs:      k,
+		children: ch,
+	}, nil
+}
+
+func (c *Cursor) top() *cursorFrame {
+	return &c.stack[len(c.stack)-1]
+}
+
+// descend pushes the path to the leftmost (first) or rightmost (last)
+// key/value of the subtree at the address to the stack.
+func (c *Cursor) descend(a store.Address, first bool) error {
+	for {
+		f, err := c.loadFrame(a)
+		if err != nil {
+			return err
+		}
+
+		if first {
+			f.idx = 0
+		} else if f.isLeaf() {
+			f.idx = len(f.kvs) - 1
+		} else {
+			f.idx = len(f.children) - 1
+		}
+
+		c.stack = append(c.stack, f)
+
+		if f.isLeaf() {
+			if len(f.kvs) == 0 {
+				c.stack = nil
+			}
+			return nil
+		}
+
+		a = f.children[f.idx]
+	}
+}
+
+// First positions the cursor at the smallest key.
+func (c *Cursor) First() error {
+	c.stack = nil
+	return c.descend(c.met.root(), true)
+}
+
+// Last positions the cursor at the largest key.
+func (c *Cursor) Last() error {
+	c.stack = nil
+	return c.descend(c.met.root(), false)
+}
+
+// Seek positions the cursor at the smallest key that is greater or equal to
+// the key.
+func (c *Cursor) Seek(key []byte) error {
+	c.stack = nil
+
+	a := c.met.root()
+
+	for {
+		f, err := c.loadFrame(a)
+		if err != nil {
+			return err
+		}
+
+		f.idx = sort.Search(len(f.kvs), func(i int) bool {
+			return bytes.Compare(f.kvs[i].key, key) >= 0
+		})
+
+		c.stack = append(c.stack, f)
+
+		if f.idx < len(f.kvs) && bytes.Equal(f.kvs[f.idx].key, key) {
+			return nil
+		}
+
+		if f.isLeaf() {
+			if f.idx < len(f.kvs) {
+				return nil
+			}
+			c.ascendForward()
+			return nil
+		}
+
+		a = f.children[f.idx]
+	}
+}
+
+// ascendForward pops exhausted nodes from the stack until it reaches the
+// parent that has the next key/value.
+func (c *Cursor) ascendForward() {
+	c.stack = c.stack[:len(c.stack)-1]
+	for len(c.stack) > 0 {
+		t := c.top()
+		if t.idx < len(t.kvs) {
+			return
+		}
+		c.stack = c.stack[:len(c.stack)-1]
+	}
+	c.stack = nil
+}
+
+// ascendBackward pops exhausted nodes from the stack until it reaches the
+// parent that has the previous key/value.
+func (c *Cursor) ascendBackward() {
+	c.stack = c.stack[:len(c.stack)-1]
+	for len(c.stack) > 0 {
+		t := c.top()
+		if t.idx > 0 {
+			t.idx--
+			return
+		}
+		c.stack = c.stack[:len(c.stack)-1]
+	}
+	c.stack = nil
+}
+
+// Next moves the cursor to the next key.
+func (c *Cursor) Next() error {
+	if !c.Valid() {
+		return errors.New("cursor is not positioned")
+	}
+
+	t := c.top()
+
+	if t.isLeaf() {
+		t.idx++
+		if t.idx == len(t.kvs) {
+			c.ascendForward()
+		}
+		return nil
+	}
+
+	t.idx++
+	return c.descend(t.children[t.idx], true)
+}
+
+// Prev moves the cursor to the previous key.
+func (c *Cursor) Prev() error {
+	if !c.Valid() {
+		return errors.New("cursor is not positioned")
+	}
+
+	t := c.top()
+
+	if t.isLeaf() {
+		t.idx--
+		if t.idx < 0 {
+			c.ascendBackward()
+		}
+		return nil
+	}
+
+	return c.descend(t.children[t.idx], false)
+}
+
+// Valid returns true if the cursor is positioned at a key.
+func (c *Cursor) Valid() bool {
+	return len(c.stack) > 0
+}
+
+func (c *Cursor) Key() []byte {
+	t := c.top()
+	return t.kvs[t.idx].key
+}
+
+func (c *Cursor) Value() store.Address {
+	t := c.top()
+	return t.kvs[t.idx].value
+}
@@ -0,0 +1,14 @@
+package btree
+
+import (
+	"github.com/draganm/l5db/store"
+)
+
+func Delete(m store.Memory, a store.Address, key []byte) error {
+	met, err := getMetaNode(m, a)
+	if err != nil {
+		return err
+	}
+
+	return met.delete(key)
+}
@@ -0,0 +1,16 @@
+package btree
+
+import (
+	"github.com/draganm/l5db/store"
+)
+
+// Free releases all blocks of the btree.
+// freeValue is called for every value stored in the btree.
+func Free(m store.Memory, a store.Address, freeValue func(store.Address) error) error {
+	met, err := getMetaNode(m, a)
+	if err != nil {
+		return err
+	}
+
+	return met.free(freeValue)
+}
@@ -28,6 +28,10 @@ type internalNode struct {
 
 func createInternalNode(m store.Memory, t byte, keySizeHint uint16, kvs kvs, children children) (store.Address, internalNode, error) {
 	expectedSize := 1 + (2+int(keySizeHint)+8)*(2*int(t)) + 8*(2*int(t)+1)
+	if ns := internalNodeSize(kvs, children); ns > expectedSize {
+		expectedSize = ns
+	}
+
 	ad, bl, err := m.Allocate(expectedSize, store.BTreeInternalNodeBlockType)
 	if err != nil {
 		return store.NilAddress, internalNode{}, errors.Wrap(err, "while allocationg empty btree internalNode")
@@ -48,7 +52,7 @@ func createInternalNode(m store.Memory, t byte, keySizeHint uint16, kvs kvs, chi
 		return store.NilAddress, internalNode{}, err
 	}
 
-	return ad, in, nil
+	return in.addr, in, nil
 }
 
 func copyByteSlice(b []byte) []byte {
@@ -64,7 +68,7 @@ func loadInternalNode(m store.Memory, a store.Address, t byte, keySizeHint uint1
 	}
 
 	if tp != store.BTreeInternalNodeBlockType {
-		return internalNode{}, errors.Wrap(err, "trying to load non- btree internal node as btree internal node")
+		return internalNode{}, errors.New("trying to load non- btree internal node as btree internal node")
 	}
 
 	cnt := int(bl[0])
@@ -72,20 +76,20 @@ func loadInternalNode(m store.Memory, a store.Address, t byte, keySizeHint uint1
 	d := bl[1:]
 	for i := 0; i < cnt; i++ {
 		if len(d) < 2 {
-			return internalNode{}, errors.New("btree internalNode malformated: not enough bytes for key length")
+			return internalNode{}, errors.Wrap(store.ErrCorrupt, "btree internalNode malformated: not enough bytes for key length")
 		}
 		l := int(binary.LittleEndian.Uint16(d))
 		d = d[2:]
 
 		if len(d) < l {
-			return internalNode{}, errors.New("btree internalNode malformated: not enough bytes for bytes")
+			return internalNode{}, errors.Wrap(store.ErrCorrupt, "btree internalNode malformated: not enough bytes for bytes")
 		}
 
 		k := d[:l]
 		d = d[l:]
 
 		if len(d) < 8 {
-			return internalNode{}, errors.New("btree internalNode malformated: not enough bytes for value address")
+			return internalNode{}, errors.Wrap(store.ErrCorrupt, "btree internalNode malformated: not enough bytes for value address")
 		}
 
 		kvs[i].key = copyByteSlice(k)
@@ -97,7 +101,7 @@ func loadInternalNode(m store.Memory, a store.Address, t byte, keySizeHint uint1
 
 	for i := 0; i < cnt+1; i++ {
 		if len(d) < 8 {
-			return internalNode{}, errors.New("btree internalNode malformated: not enough bytes for child address")
+			return internalNode{}, errors.Wrap(store.ErrCorrupt, "btree internalNode malformated: not enough bytes for child address")
 		}
 		children[i] = store.Address(binary.LittleEndian.Uint64(d))
 		d = d[8:]
@@ -239,10 +243,334 @@ func (i internalNode) get(key []byte) (store.Address, error) {
 	return ch.get(key)
 }
 
+func (i internalNode) delete(key []byte) (store.Address, error) {
+
+	lsr := i.localSearch(key)
+
+	if lsr.isLocalKV() {
+		idx := lsr.kvIndex
+
+		left, err := getNode(i.m, i.children[idx], i.t, i.keySizeHint)
+		if err != nil {
+			return store.NilAddress, errors.Wrap(err, "while getting left child")
+		}
+
+		if left.keyCount() >= int(i.t) {
+			pred, err := left.max()
+			if err != nil {
+				return store.NilAddress, errors.Wrap(err, "while getting predecessor")
+			}
+
+			na, err := left.delete(pred.key)
+			if err != nil {
+				return store.NilAddress, errors.Wrap(err, "while deleting predecessor")
+			}
+
+			i.kvs[idx] = pred
+			i.children[idx] = na
+
+			err = i.store()
+			if err != nil {
+				return store.NilAddress, err
+			}
+
+			return i.addr, nil
+		}
+
+		right, err := getNode(i.m, i.children[idx+1], i.t, i.keySizeHint)
+		if err != nil {
+			return store.NilAddress, errors.Wrap(err, "while getting right child")
+		}
+
+		if right.keyCount() >= int(i.t) {
+			succ, err := right.min()
+			if err != nil {
+				return store.NilAddress, errors.Wrap(err, "while getting successor")
+			}
+
+			na, err := right.delete(succ.key)
+			if err != nil {
+				return store.NilAddress, errors.Wrap(err, "while deleting successor")
+			}
+
+			i.kvs[idx] = succ
+			i.children[idx+1] = na
+
+			err = i.store()
+			if err != nil {
+				return store.NilAddress, err
+			}
+
+			return i.addr, nil
+		}
+
+		err = i.mergeChildren(idx)
+		if err != nil {
+			return store.NilAddress, err
+		}
+
+		return i.deleteFromChild(idx, key)
+	}
+
+	ci := lsr.childIndex
+
+	child, err := getNode(i.m, i.children[ci], i.t, i.keySizeHint)
+	if err != nil {
+		return store.NilAddress, errors.Wrap(err, "while getting child")
+	}
+
+	if child.keyCount() < int(i.t) {
+		ci, err = i.fillChild(ci)
+		if err != nil {
+			return store.NilAddress, errors.Wrap(err, "while filling child")
+		}
+	}
+
+	return i.deleteFromChild(ci, key)
+}
+
+func (i *internalNode) deleteFromChild(ci int, key []byte) (store.Address, error) {
+	child, err := getNode(i.m, i.children[ci], i.t, i.keySizeHint)
+	if err != nil {
+		return store.NilAddress, errors.Wrap(err, "while getting child")
+	}
+
+	nca, err := child.delete(key)
+	if err != nil {
+		return store.NilAddress, err
+	}
+
+	i.children[ci] = nca
+
+	err = i.store()
+	if err != nil {
+		return store.NilAddress, errors.Wrap(err, "while storing internal node")
+	}
+
+	return i.addr, nil
+}
+
+// fillChild makes sure that the child at index ci has at least t keys
+// by either borrowing a key from one of its siblings or merging it with
+// a sibling.
+// Returns the index of the child that contains the keys of the original
+// child.
+func (i *internalNode) fillChild(ci int) (int, error) {
+	if ci > 0 {
+		left, err := getNode(i.m, i.children[ci-1], i.t, i.keySizeHint)
+		if err != nil {
+			return 0, errors.Wrap(err, "while getting left sibling")
+		}
+		if left.keyCount() >= int(i.t) {
+			return ci, i.borrowFromLeft(ci)
+		}
+	}
+
+	if ci < len(i.kvs) {
+		right, err := getNode(i.m, i.children[ci+1], i.t, i.keySizeHint)
+		if err != nil {
+			return 0, errors.Wrap(err, "while getting right sibling")
+		}
+		if right.keyCount() >= int(i.t) {
+			return ci, i.borrowFromRight(ci)
+		}
+		return ci, i.mergeChildren(ci)
+	}
+
+	return ci - 1, i.mergeChildren(ci - 1)
+}
+
+// borrowFromLeft moves the separating key down to the front of the child at
+// index ci and the last key of its left sibling up in its place.
+func (i *internalNode) borrowFromLeft(ci int) error {
+	left, err := getNode(i.m, i.children[ci-1], i.t, i.keySizeHint)
+	if err != nil {
+		return errors.Wrap(err, "while getting left sibling")
+	}
+
+	child, err := getNode(i.m, i.children[ci], i.t, i.keySizeHint)
+	if err != nil {
+		return errors.Wrap(err, "while getting child")
+	}
+
+	lk, lc := left.content()
+	ck, cc := child.content()
+
+	ck = append(kvs{i.kvs[ci-1]}, ck...)
+	i.kvs[ci-1] = lk[len(lk)-1]
+	lk = lk[:len(lk)-1]
+
+	if lc != nil {
+		cc = append(children{lc[len(lc)-1]}, cc...)
+		lc = lc[:len(lc)-1]
+	}
+
+	la, err := left.setContent(lk, lc)
+	if err != nil {
+		return errors.Wrap(err, "while storing left sibling")
+	}
+
+	ca, err := child.setContent(ck, cc)
+	if err != nil {
+		return errors.Wrap(err, "while storing child")
+	}
+
+	i.children[ci-1] = la
+	i.children[ci] = ca
+
+	return i.store()
+}
+
+// borrowFromRight moves the separating key down to the end of the child at
+// index ci and the first key of its right sibling up in its place.
+func (i *internalNode) borrowFromRight(ci int) error {
+	child, err := getNode(i.m, i.children[ci], i.t, i.keySizeHint)
+	if err != nil {
+		return errors.Wrap(err, "while getting child")
+	}
+
+	right, err := getNode(i.m, i.children[ci+1], i.t, i.keySizeHint)
+	if err != nil {
+		return errors.Wrap(err, "while getting right sibling")
+	}
+
+	ck, cc := child.content()
+	rk, rc := right.content()
+
+	ck = append(ck, i.kvs[ci])
+	i.kvs[ci] = rk[0]
+	rk = rk[1:]
+
+	if rc != nil {
+		cc = append(cc, rc[0])
+		rc = rc[1:]
+	}
+
+	ca, err := child.setContent(ck, cc)
+	if err != nil {
+		return errors.Wrap(err, "while storing child")
+	}
+
+	ra, err := right.setContent(rk, rc)
+	if err != nil {
+		return errors.Wrap(err, "while storing right sibling")
+	}
+
+	i.children[ci] = ca
+	i.children[ci+1] = ra
+
+	return i.store()
+}
+
+// mergeChildren merges the child at index ci+1 and the separating key into
+// the child at index ci.
+func (i *internalNode) mergeChildren(ci int) error {
+	left, err := getNode(i.m, i.children[ci], i.t, i.keySizeHint)
+	if err != nil {
+		return errors.Wrap(err, "while getting left child")
+	}
+
+	right, err := getNode(i.m, i.children[ci+1], i.t, i.keySizeHint)
+	if err != nil {
+		return errors.Wrap(err, "while getting right child")
+	}
+
+	lk, lc := left.content()
+	rk, rc := right.content()
+
+	lk = append(append(lk, i.kvs[ci]), rk...)
+
+	if lc != nil {
+		lc = append(lc, rc...)
+	}
+
+	la, err := left.setContent(lk, lc)
+	if err != nil {
+		return errors.Wrap(err, "while storing merged child")
+	}
+
+	err = i.m.Free(i.children[ci+1])
+	if err != nil {
+		return errors.Wrap(err, "while freeing merged right child")
+	}
+
+	i.kvs = append(i.kvs[:ci], i.kvs[ci+1:]...)
+	i.children = append(i.children[:ci+1], i.children[ci+2:]...)
+	i.children[ci] = la
+
+	return i.store()
+}
+
+func (i internalNode) min() (kv, error) {
+	ch, err := getNode(i.m, i.children[0], i.t, i.keySizeHint)
+	if err != nil {
+		return kv{}, errors.Wrap(err, "while getting child")
+	}
+	return ch.min()
+}
+
+func (i internalNode) max() (kv, error) {
+	ch, err := getNode(i.m, i.children[len(i.children)-1], i.t, i.keySizeHint)
+	if err != nil {
+		return kv{}, errors.Wrap(err, "while getting child")
+	}
+	return ch.max()
+}
+
+func (i internalNode) content() (kvs, children) {
+	return i.kvs.copy(), i.children.copy()
+}
+
+func (i internalNode) setContent(k kvs, c children) (store.Address, error) {
+	i.kvs = k
+	i.children = c
+	err := i.store()
+	if err != nil {
+		return store.NilAddress, err
+	}
+	return i.addr, nil
+}
+
+func (i internalNode) free(freeValue func(store.Address) error) error {
+	for _, kv := range i.kvs {
+		err := freeValue(kv.value)
+		if err != nil {
+			return errors.Wrapf(err, "while freeing value %d", kv.value)
+		}
+	}
+
+	for _, c := range i.children {
+		ch, err := getNode(i.m, c, i.t, i.keySizeHint)
+		if err != nil {
+			return errors.Wrap(err, "while getting child")
+		}
+
+		err = ch.free(freeValue)
+		if err != nil {
+			return err
+		}
+	}
+
+	return i.m.Free(i.addr)
+}
+
 func (i internalNode) keyCount() int {
-	return int(i.bl[0])
+	return len(i.kvs)
+}
+
+func internalNodeSize(kvs kvs, children children) int {
+	totalSize := 1 + len(children)*8
+
+	for _, kv := range kvs {
+		totalSize += 2 + len(kv.key) + 8
+	}
+
+	return totalSize
 }
 
+// store writes the kvs and children to the internal node's block.
+// If they don't fit into the block, the internal node is moved to a new,
+// larger block and the old one is freed.
 func (i *internalNode) store() error {
 
 	if len(i.kvs) != len(i.children)-1 {
@@ -253,14 +581,21 @@ func (i *internalNode) store() error {
 		return errors.Errorf("trying to save %d key/values, max %d is allowed", len(i.kvs), (2 * i.t))
 	}
 
-	totalSize := 1 + len(i.children)*8
-
-	for _, kv := range i.kvs {
-		totalSize += 2 + len(kv.key) + 8
-	}
+	totalSize := internalNodeSize(i.kvs, i.children)
 
 	if totalSize > len(i.bl) {
-		return errors.New("TODO: implement allocating larger block")
+		na, bl, err := i.m.Allocate(totalSize, store.BTreeInternalNodeBlockType)
+		if err != nil {
+			return errors.Wrap(err, "while allocating larger btree internal node")
+		}
+
+		err = i.m.Free(i.addr)
+		if err != nil {
+			return errors.Wrap(err, "while freeing btree internal node")
+		}
+
+		i.addr = na
+		i.bl = bl
 	}
 
 	d := i.bl
@@ -26,6 +26,10 @@ type leaf struct {
 
 func createLeaf(m store.Memory, t byte, keySizeHint uint16, kvs kvs) (store.Address, leaf, error) {
 	expectedSize := 1 + (2+int(keySizeHint)+8)*(2*int(t))
+	if ls := leafSize(kvs); ls > expectedSize {
+		expectedSize = ls
+	}
+
 	ad, bl, err := m.Allocate(expectedSize, store.BTreeLeafBlockType)
 	if err != nil {
 		return store.NilAddress, leaf{}, errors.Wrap(err, "while allocationg empty btree leaf")
@@ -45,7 +49,7 @@ func createLeaf(m store.Memory, t byte, keySizeHint uint16, kvs kvs) (store.Addr
 		return store.NilAddress, leaf{}, err
 	}
 
-	return ad, l, nil
+	return l.addr, l, nil
 }
 
 func loadLeaf(m store.Memory, a store.Address, t byte, keySizeHint uint16) (leaf, error) {
@@ -63,20 +67,20 @@ func loadLeaf(m store.Memory, a store.Address, t byte, keySizeHint uint16) (leaf
 	d := bl[1:]
 	for i := 0; i < cnt; i++ {
 		if len(d) < 2 {
-			return leaf{}, errors.New("btree leaf malformated: not enough bytes for key length")
+			return leaf{}, errors.Wrap(store.ErrCorrupt, "btree leaf malformated: not enough bytes for key length")
 		}
 		l := int(binary.LittleEndian.Uint16(d))
 		d = d[2:]
 
 		if len(d) < l {
-			return leaf{}, errors.New("btree leaf malformated: not enough bytes for bytes")
+			return leaf{}, errors.Wrap(store.ErrCorrupt, "btree leaf malformated: not enough bytes for bytes")
 		}
 
 		k := d[:l]
 		d = d[l:]
 
 		if len(d) < 8 {
-			return leaf{}, errors.New("btree leaf malformated: not enough bytes for value address")
+			return leaf{}, errors.Wrap(store.ErrCorrupt, "btree leaf malformated: not enough bytes for value address")
 		}
 
 		kvs[i].key = copyByteSlice(k)
@@ -143,11 +147,81 @@ func (l leaf) get(key []byte) (store.Address, error) {
 	return store.NilAddress, ErrNotFound
 }
 
+func (l leaf) delete(key []byte) (store.Address, error) {
+
+	idx := sort.Search(len(l.kvs), func(i int) bool {
+		return bytes.Compare(l.kvs[i].key, key) >= 0
+	})
+
+	if idx == len(l.kvs) || !bytes.Equal(l.kvs[idx].key, key) {
+		return store.NilAddress, ErrNotFound
+	}
+
+	l.kvs = append(l.kvs[:idx], l.kvs[idx+1:]...)
+	err := l.store()
+	if err != nil {
+		return store.NilAddress, errors.Wrap(err, "while storing kvs")
+	}
+
+	return l.addr, nil
+}
+
+func (l leaf) min() (kv, error) {
+	if len(l.kvs) == 0 {
+		return kv{}, errors.New("trying to get min of an empty leaf")
+	}
+	return l.kvs[0].copy(), nil
+}
+
+func (l leaf) max() (kv, error) {
+	if len(l.kvs) == 0 {
+		return kv{}, errors.New("trying to get max of an empty leaf")
+	}
+	return l.kvs[len(l.kvs)-1].copy(), nil
+}
+
+func (l leaf) content() (kvs, children) {
+	return l.kvs.copy(), nil
+}
+
+func (l leaf) setContent(k kvs, _ children) (store.Address, error) {
+	l.kvs = k
+	err := l.store()
+	if err != nil {
+		return store.NilAddress, err
+	}
+	return l.addr, nil
+}
+
+func (l leaf) free(freeValue func(store.Address) error) error {
+	for _, kv := range l.kvs {
+		err := freeValue(kv.value)
+		if err != nil {
+			return errors.Wrapf(err, "while freeing value %d", kv.value)
+		}
+	}
+
+	return l.m.Free(l.addr)
+}
+
 func (l leaf) keyCount() int {
 	return len(l.kvs)
 }
 
-func (l leaf) store() error {
+func leafSize(kvs kvs) int {
+	totalSize := 1
+
+	for _, kv := range kvs {
+		totalSize += 2 + len(kv.key) + 8
+	}
+
+	return totalSize
+}
+
+// store writes the kvs to the leaf's block.
+// If the kvs don't fit into the block, the leaf is moved to a new,
+// larger block and the old one is freed.
+func (l *leaf) store() error {
 
 	isSorted := sort.SliceIsSorted(l.kvs, func(j, k int) bool {
 		return bytes.Compare(l.kvs[j].key, l.kvs[k].key) < 0
@@ -163,14 +237,21 @@ func (l leaf) store() error {
 		}
 	}
 
-	totalSize := 1
-
-	for _, kv := range l.kvs {
-		totalSize += 2 + len(kv.key) + 8
-	}
+	totalSize := leafSize(l.kvs)
 
 	if totalSize > len(l.bl) {
-		return errors.New("TODO: implement allocating larger block")
+		na, bl, err := l.m.Allocate(totalSize, store.BTreeLeafBlockType)
+		if err != nil {
+			return errors.Wrap(err, "while allocating larger btree leaf")
+		}
+
+		err = l.m.Free(l.addr)
+		if err != nil {
+			return errors.Wrap(err, "while freeing btree leaf")
+		}
+
+		l.addr = na
+		l.bl = bl
 	}
 
 	d := l.bl
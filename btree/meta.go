@@ -19,8 +19,10 @@ type meta struct {
 // 2 bytes - key size hint
 // 1 byte - t
 
+const metaSize = 19
+
 func createMeta(m store.Memory, t byte, keySizeHint uint16) (store.Address, meta, error) {
-	a, d, err := m.Allocate(19, store.BTreeMetaBlockType)
+	a, d, err := m.Allocate(metaSize, store.BTreeMetaBlockType)
 	if err != nil {
 		return store.NilAddress, meta{}, errors.Wrap(err, "while allocating btree meta data")
 	}
@@ -44,7 +46,11 @@ func getMetaNode(m store.Memory, a store.Address) (meta, error) {
 	}
 
 	if t != store.BTreeMetaBlockType {
-		return meta{}, errors.Wrap(err, "block is not btree meta block")
+		return meta{}, errors.New("block is not btree meta block")
+	}
+
+	if len(b) < metaSize {
+		return meta{}, errors.Wrapf(store.ErrCorrupt, "btree meta block at %d has less than %d bytes", a, metaSize)
 	}
 
 	return meta{
@@ -63,6 +69,11 @@ func (m meta) incrementCount() {
 	m.m.Touch(m.addr)
 }
 
+func (m meta) decrementCount() {
+	binary.LittleEndian.PutUint64(m.bl, m.count()-1)
+	m.m.Touch(m.addr)
+}
+
 func (m meta) root() store.Address {
 	return store.Address(binary.LittleEndian.Uint64(m.bl[8:]))
 }
@@ -112,6 +123,61 @@ func (m meta) put(key []byte, value store.Address) error {
 	return nil
 }
 
+func (m meta) delete(key []byte) error {
+
+	_, err := m.get(key)
+	if err != nil {
+		return err
+	}
+
+	rt, err := m.getRootNode()
+	if err != nil {
+		return err
+	}
+
+	na, err := rt.delete(key)
+	if err != nil {
+		return err
+	}
+
+	if na != m.root() {
+		m.setRoot(na)
+	}
+
+	rt, err = m.getRootNode()
+	if err != nil {
+		return err
+	}
+
+	k, ch := rt.content()
+	if len(k) == 0 && len(ch) == 1 {
+		oldRoot := m.root()
+		m.setRoot(ch[0])
+		err = m.m.Free(oldRoot)
+		if err != nil {
+			return errors.Wrap(err, "while freeing old root")
+		}
+	}
+
+	m.decrementCount()
+
+	return nil
+}
+
+func (m meta) free(freeValue func(store.Address) error) error {
+	rt, err := m.getRootNode()
+	if err != nil {
+		return err
+	}
+
+	err = rt.free(freeValue)
+	if err != nil {
+		return err
+	}
+
+	return m.m.Free(m.addr)
+}
+
 func (m meta) getRootNode() (btreeNode, error) {
 	return getNode(m.m, m.root(), m.t(), m.keySizeHint())
 }
@@ -0,0 +1,23 @@
+package btree
+
+import "github.com/draganm/l5db/store"
+
+// Order returns the order (t) of the btree.
+func Order(m store.Memory, a store.Address) (byte, error) {
+	met, err := getMetaNode(m, a)
+	if err != nil {
+		return 0, err
+	}
+
+	return met.t(), nil
+}
+
+// KeySizeHint returns the key size the nodes of the btree are sized for.
+func KeySizeHint(m store.Memory, a store.Address) (uint16, error) {
+	met, err := getMetaNode(m, a)
+	if err != nil {
+		return 0, err
+	}
+
+	return met.keySizeHint(), nil
+}
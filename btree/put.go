@@ -1,10 +1,17 @@
 package btree
 
 import (
+	"math"
+
 	"github.com/draganm/l5db/store"
+	"github.com/pkg/errors"
 )
 
 func Put(m store.Memory, a store.Address, key []byte, value store.Address) error {
+	if len(key) > math.MaxUint16 {
+		return errors.Errorf("key is %d bytes long, max %d bytes are supported", len(key), math.MaxUint16)
+	}
+
 	met, err := getMetaNode(m, a)
 	if err != nil {
 		return err
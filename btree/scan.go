@@ -0,0 +1,122 @@
+package btree
+
+import (
+	"bytes"
+
+	"github.com/draganm/l5db/store"
+)
+
+// Range selects keys of a btree for Scan.
+type Range struct {
+	// Start is the smallest key included in the range, nil for no lower bound.
+	Start []byte
+	// End is the first key not included in the range, nil for no upper bound.
+	End []byte
+	// Reverse scans from the largest to the smallest key.
+	Reverse bool
+	// Limit is the maximum number of keys scanned, 0 for no limit.
+	Limit int
+}
+
+// PrefixRange returns the range of all keys starting with the prefix.
+func PrefixRange(prefix []byte) Range {
+	return Range{
+		Start: copyByteSlice(prefix),
+		End:   prefixEnd(prefix),
+	}
+}
+
+// prefixEnd returns the smallest key that is greater than all keys with the
+// prefix, or nil if there is no such key.
+func prefixEnd(prefix []byte) []byte {
+	end := copyByteSlice(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+func (r Range) contains(key []byte) bool {
+	if r.Start != nil && bytes.Compare(key, r.Start) < 0 {
+		return false
+	}
+
+	if r.End != nil && bytes.Compare(key, r.End) >= 0 {
+		return false
+	}
+
+	return true
+}
+
+// Scan calls fn for every key in the range in key order, or reverse key
+// order if the range is reversed.
+// Returning an error from fn stops the scan and the error is returned by
+// Scan.
+func Scan(m store.Memory, a store.Address, r Range, fn func(key []byte, value store.Address) error) error {
+	c, err := NewCursor(m, a)
+	if err != nil {
+		return err
+	}
+
+	if r.Reverse {
+		err = c.seekLast(r.End)
+	} else {
+		err = c.seekFirst(r.Start)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for n := 0; c.Valid() && (r.Limit == 0 || n < r.Limit); n++ {
+		if !r.contains(c.Key()) {
+			return nil
+		}
+
+		err = fn(c.Key(), c.Value())
+		if err != nil {
+			return err
+		}
+
+		if r.Reverse {
+			err = c.Prev()
+		} else {
+			err = c.Next()
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// seekFirst positions the cursor at the first key greater or equal to start.
+func (c *Cursor) seekFirst(start []byte) error {
+	if start == nil {
+		return c.First()
+	}
+	return c.Seek(start)
+}
+
+// seekLast positions the cursor at the last key smaller than end.
+func (c *Cursor) seekLast(end []byte) error {
+	if end == nil {
+		return c.Last()
+	}
+
+	err := c.Seek(end)
+	if err != nil {
+		return err
+	}
+
+	if !c.Valid() {
+		return c.Last()
+	}
+
+	return c.Prev()
+}
@@ -0,0 +1,145 @@
+package btree
+
+import (
+	"bytes"
+
+	"github.com/draganm/l5db/store"
+	"github.com/pkg/errors"
+)
+
+// Verify checks the structure of the btree: block types, ascending order
+// of keys, the number of keys in each node, the depth of leaves and the
+// count of keys stored in the meta block.
+// visitBlock is called for every block of the btree and verifyValue for
+// every key and value stored in the btree. Errors returned by the callbacks
+// stop the verification.
+func Verify(m store.Memory, a store.Address, visitBlock func(store.Address) error, verifyValue func(key []byte, value store.Address) error) error {
+	err := visitBlock(a)
+	if err != nil {
+		return err
+	}
+
+	met, err := getMetaNode(m, a)
+	if err != nil {
+		return err
+	}
+
+	if met.t() < 2 {
+		return errors.Errorf("btree %d has invalid order %d", a, met.t())
+	}
+
+	v := verifier{
+		m:           m,
+		t:           met.t(),
+		keySizeHint: met.keySizeHint(),
+		visitBlock:  visitBlock,
+		verifyValue: verifyValue,
+	}
+
+	cnt, _, err := v.verifyNode(met.root(), bound{}, bound{}, true)
+	if err != nil {
+		return err
+	}
+
+	if cnt != met.count() {
+		return errors.Errorf("btree %d has count %d, but contains %d keys", a, met.count(), cnt)
+	}
+
+	return nil
+}
+
+// bound is a lower or upper limit of keys in a subtree.
+type bound struct {
+	key   []byte
+	isSet bool
+}
+
+type verifier struct {
+	m           store.Memory
+	t           byte
+	keySizeHint uint16
+	visitBlock  func(store.Address) error
+	verifyValue func(key []byte, value store.Address) error
+}
+
+// verifyNode verifies the subtree of the node with all keys between lower
+// and upper and returns the number of keys and the height of the subtree.
+func (v verifier) verifyNode(a store.Address, lower, upper bound, isRoot bool) (uint64, int, error) {
+	err := v.visitBlock(a)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	n, err := getNode(v.m, a, v.t, v.keySizeHint)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "while loading btree node %d", a)
+	}
+
+	keys, children := n.content()
+
+	if len(keys) > 2*int(v.t)-1 {
+		return 0, 0, errors.Errorf("btree node %d has %d keys, more than maximum of %d", a, len(keys), 2*int(v.t)-1)
+	}
+
+	if !isRoot && len(keys) < int(v.t)-1 {
+		return 0, 0, errors.Errorf("btree node %d has %d keys, less than minimum of %d", a, len(keys), int(v.t)-1)
+	}
+
+	for i, kv := range keys {
+		if i > 0 && bytes.Compare(keys[i-1].key, kv.key) >= 0 {
+			return 0, 0, errors.Errorf("keys of btree node %d are not in ascending order", a)
+		}
+
+		if lower.isSet && bytes.Compare(kv.key, lower.key) <= 0 || upper.isSet && bytes.Compare(kv.key, upper.key) >= 0 {
+			return 0, 0, errors.Errorf("key %q of btree node %d is outside of the range of its parent", kv.key, a)
+		}
+
+		err = v.verifyValue(kv.key, kv.value)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	cnt := uint64(len(keys))
+
+	_, isLeaf := n.(leaf)
+	if isLeaf {
+		return cnt, 0, nil
+	}
+
+	if len(keys) == 0 {
+		return 0, 0, errors.Errorf("btree internal node %d has no keys", a)
+	}
+
+	if len(children) != len(keys)+1 {
+		return 0, 0, errors.Errorf("btree internal node %d has %d keys and %d children", a, len(keys), len(children))
+	}
+
+	height := 0
+
+	for i, ch := range children {
+		lo, hi := lower, upper
+
+		if i > 0 {
+			lo = bound{key: keys[i-1].key, isSet: true}
+		}
+
+		if i < len(keys) {
+			hi = bound{key: keys[i].key, isSet: true}
+		}
+
+		c, h, err := v.verifyNode(ch, lo, hi, false)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		if i > 0 && h != height {
+			return 0, 0, errors.Errorf("leaves of btree internal node %d are not at the same depth", a)
+		}
+
+		height = h
+		cnt += c
+	}
+
+	return cnt, height + 1, nil
+}
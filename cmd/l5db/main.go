@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/draganm/l5db"
+)
+
+const usage = `usage: l5db <command> <dir>
+
+commands:
+  verify    check the consistency of the database in dir
+`
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	command, dir := os.Args[1], os.Args[2]
+
+	switch command {
+	case "verify":
+		err := verify(dir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n%s", command, usage)
+		os.Exit(2)
+	}
+}
+
+func verify(dir string) error {
+	db, err := l5db.OpenWithOptions(dir, l5db.Options{
+		ReadOnly:        true,
+		VerifyChecksums: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	err = db.Verify()
+
+	var ve *l5db.VerificationError
+	if errors.As(err, &ve) {
+		for _, p := range ve.Problems {
+			fmt.Println(p)
+		}
+		return fmt.Errorf("found %d problems", len(ve.Problems))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("ok")
+
+	return nil
+}
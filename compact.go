@@ -0,0 +1,218 @@
+package l5db
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/draganm/l5db/btree"
+	"github.com/draganm/l5db/sequential"
+	"github.com/draganm/l5db/store"
+	"github.com/pkg/errors"
+)
+
+// CompactResult reports the sizes of the database files before and after
+// compaction.
+type CompactResult struct {
+	SizeBefore int64
+	SizeAfter  int64
+}
+
+// Compact copies all maps and values of the database in srcDir into a new
+// database in dstDir.
+// Only maps and values reachable from the root are copied, so the new
+// database file contains no free blocks. Values keep their block size, only
+// the last data block of a value is sized to fit its data. Blocks are still
+// rounded up to a power of two, so some unused space remains.
+// The source database is opened read-only and must not be opened by a
+// writer.
+func Compact(srcDir, dstDir string) (CompactResult, error) {
+	src, err := OpenWithOptions(srcDir, Options{ReadOnly: true})
+	if err != nil {
+		return CompactResult{}, errors.Wrap(err, "while opening source database")
+	}
+
+	defer src.Close()
+
+	dst, err := store.OpenWithOptions(dstDir, store.Options{
+		MaxSize:  src.options.MaxMapSize,
+		FileMode: src.options.FileMode,
+	})
+	if err != nil {
+		return CompactResult{}, errors.Wrap(err, "while opening destination store")
+	}
+
+	defer dst.Close()
+
+	if dst.GetRootAddress() != store.NilAddress {
+		return CompactResult{}, errors.Errorf("%s already contains a database", dstDir)
+	}
+
+	tx, err := dst.PrivateMMap()
+	if err != nil {
+		return CompactResult{}, errors.Wrap(err, "while creating private MMAP for compaction")
+	}
+
+	root, err := copyValue(src.st, tx, src.st.GetRootAddress())
+	if err != nil {
+		tx.Rollback()
+		return CompactResult{}, errors.Wrap(err, "while copying root")
+	}
+
+	err = tx.SetRootAddress(root)
+	if err != nil {
+		tx.Rollback()
+		return CompactResult{}, errors.Wrap(err, "while setting root address")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return CompactResult{}, err
+	}
+
+	err = dst.Trim()
+	if err != nil {
+		return CompactResult{}, err
+	}
+
+	before, err := fileSize(filepath.Join(srcDir, "db"))
+	if err != nil {
+		return CompactResult{}, err
+	}
+
+	after, err := fileSize(filepath.Join(dstDir, "db"))
+	if err != nil {
+		return CompactResult{}, err
+	}
+
+	return CompactResult{
+		SizeBefore: before,
+		SizeAfter:  after,
+	}, nil
+}
+
+func fileSize(name string) (int64, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return 0, errors.Wrapf(err, "while getting stats of %s", name)
+	}
+
+	return fi.Size(), nil
+}
+
+// copyValue copies the map or value at the address from src to dst and
+// returns its address in dst.
+func copyValue(src, dst store.Memory, a store.Address) (store.Address, error) {
+	_, t, err := src.GetBlock(a)
+	if err != nil {
+		return store.NilAddress, errors.Wrap(err, "while getting value block")
+	}
+
+	switch t {
+	case store.BTreeMetaBlockType:
+		return copyMap(src, dst, a)
+	case store.SequentialMetaBlockType:
+		return copySequential(src, dst, a)
+	case store.SequentialInlineBlockType:
+		return copyInline(src, dst, a)
+	default:
+		return store.NilAddress, errors.Errorf("unsupported value block type %d", t)
+	}
+}
+
+func copyMap(src, dst store.Memory, a store.Address) (store.Address, error) {
+	t, err := btree.Order(src, a)
+	if err != nil {
+		return store.NilAddress, err
+	}
+
+	keySizeHint, err := btree.KeySizeHint(src, a)
+	if err != nil {
+		return store.NilAddress, err
+	}
+
+	na, err := btree.CreateEmptyBTree(dst, t, keySizeHint)
+	if err != nil {
+		return store.NilAddress, err
+	}
+
+	err = btree.Scan(src, a, btree.Range{}, func(key []byte, value store.Address) error {
+		nv, err := copyValue(src, dst, value)
+		if err != nil {
+			return errors.Wrapf(err, "while copying %q", key)
+		}
+
+		return btree.Put(dst, na, key, nv)
+	})
+	if err != nil {
+		return store.NilAddress, err
+	}
+
+	return na, nil
+}
+
+func copyInline(src, dst store.Memory, a store.Address) (store.Address, error) {
+	r, err := sequential.Reader(src, a)
+	if err != nil {
+		return store.NilAddress, err
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return store.NilAddress, errors.Wrap(err, "while reading inline value")
+	}
+
+	return sequential.CreateInline(dst, data)
+}
+
+func copySequential(src, dst store.Memory, a store.Address) (store.Address, error) {
+	size, err := sequential.Size(src, a)
+	if err != nil {
+		return store.NilAddress, err
+	}
+
+	blockSize, err := sequential.BlockSize(src, a)
+	if err != nil {
+		return store.NilAddress, err
+	}
+
+	na, err := sequential.CreateEmpty(dst, blockSize)
+	if err != nil {
+		return store.NilAddress, err
+	}
+
+	r, err := sequential.Reader(src, a)
+	if err != nil {
+		return store.NilAddress, err
+	}
+
+	buf := make([]byte, blockSize)
+
+	for remaining := size; remaining > 0; {
+		chunk := buf
+		if remaining < uint64(len(chunk)) {
+			chunk = chunk[:remaining]
+		}
+
+		_, err = io.ReadFull(r, chunk)
+		if err != nil {
+			return store.NilAddress, errors.Wrap(err, "while reading value")
+		}
+
+		remaining -= uint64(len(chunk))
+
+		if remaining == 0 {
+			// the last data block doesn't need to have space for appends
+			err = sequential.AppendLast(dst, na, chunk)
+		} else {
+			err = sequential.Append(dst, na, chunk)
+		}
+
+		if err != nil {
+			return store.NilAddress, err
+		}
+	}
+
+	return na, nil
+}
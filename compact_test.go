@@ -0,0 +1,107 @@
+package l5db_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/draganm/l5db"
+	"github.com/draganm/l5db/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompact(t *testing.T) {
+	src, cleanupSrc := createTempDir(t)
+	defer cleanupSrc()
+
+	dst, cleanupDst := createTempDir(t)
+	defer cleanupDst()
+
+	db, err := l5db.Open(src)
+	require.NoError(t, err)
+
+	err = db.CreateMap("m")
+	require.NoError(t, err)
+
+	large := bytes.Repeat([]byte{1, 2, 3}, 20000)
+
+	for i := 0; i < 100; i++ {
+		err = db.Put(fmt.Sprintf("m/%03d", i), []byte(fmt.Sprintf("value %d", i)))
+		require.NoError(t, err)
+	}
+
+	for i := 0; i < 50; i++ {
+		err = db.Delete(fmt.Sprintf("m/%03d", i))
+		require.NoError(t, err)
+	}
+
+	err = db.Put("large", large)
+	require.NoError(t, err)
+
+	err = db.Put("empty", nil)
+	require.NoError(t, err)
+
+	err = db.Append("log", []byte("first entry\n"))
+	require.NoError(t, err)
+
+	err = db.Close()
+	require.NoError(t, err)
+
+	res, err := l5db.Compact(src, dst)
+	require.NoError(t, err)
+	require.True(t, res.SizeAfter < res.SizeBefore)
+
+	_, err = l5db.Compact(src, dst)
+	require.Error(t, err)
+
+	db, err = l5db.Open(dst)
+	require.NoError(t, err)
+
+	err = db.Verify()
+	require.NoError(t, err)
+
+	names, err := db.List("m")
+	require.NoError(t, err)
+	require.Len(t, names, 50)
+
+	for i := 50; i < 100; i++ {
+		d, err := db.Get(fmt.Sprintf("m/%03d", i))
+		require.NoError(t, err)
+		require.Equal(t, []byte(fmt.Sprintf("value %d", i)), d)
+	}
+
+	d, err := db.Get("large")
+	require.NoError(t, err)
+	require.Equal(t, large, d)
+
+	d, err = db.Get("empty")
+	require.NoError(t, err)
+	require.Len(t, d, 0)
+
+	entries := bytes.Repeat([]byte("next entry\n"), 3000)
+
+	err = db.Append("log", entries)
+	require.NoError(t, err)
+
+	d, err = db.Get("log")
+	require.NoError(t, err)
+	require.Equal(t, append([]byte("first entry\n"), entries...), d)
+
+	err = db.Close()
+	require.NoError(t, err)
+
+	st, err := store.Open(dst, 1024*1024*1024)
+	require.NoError(t, err)
+	defer st.Close()
+
+	// appends to compacted values use blocks of the original block size
+	dataBlocks := 0
+	err = st.Blocks(func(a store.Address, t store.BlockType) error {
+		if t == store.SequentialDataBlockType {
+			dataBlocks++
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.LessOrEqual(t, dataBlocks, 5)
+}
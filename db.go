@@ -1,6 +1,7 @@
 package l5db
 
 import (
+	"context"
 	"sync"
 
 	"github.com/draganm/l5db/btree"
@@ -9,47 +10,137 @@ import (
 )
 
 type DB struct {
-	st *store.Store
-	mu sync.Mutex
+	st      *store.Store
+	mu      sync.RWMutex
+	options Options
+
+	// writeLock is held by the single active write transaction.
+	writeLock chan struct{}
 }
 
+// ErrReadOnly is returned by all modifications of a database opened
+// read-only.
+var ErrReadOnly = store.ErrReadOnly
+
+// ErrLocked is returned when the database is locked by another process.
+var ErrLocked = store.ErrLocked
+
+// ErrCorrupt is returned when the database file is corrupted.
+var ErrCorrupt = store.ErrCorrupt
+
+// ErrFull is returned when the database file would grow larger than
+// MaxMapSize.
+var ErrFull = store.ErrFull
+
+// ErrCommitFailed is returned by all transactions after a commit failed
+// while changing the database file. The database has to be reopened to
+// recover the commit.
+var ErrCommitFailed = store.ErrCommitFailed
+
 func Open(dir string) (*DB, error) {
+	return OpenWithOptions(dir, DefaultOptions)
+}
+
+func OpenWithOptions(dir string, o Options) (*DB, error) {
+
+	o = o.withDefaults()
 
-	st, err := store.Open(dir, 1*1024*1024*1024*1024)
+	err := o.validate()
+	if err != nil {
+		return nil, errors.Wrap(err, "while validating options")
+	}
+
+	st, err := store.OpenWithOptions(dir, store.Options{
+		MaxSize:         o.MaxMapSize,
+		GrowthIncrement: o.GrowthIncrement,
+		FileMode:        o.FileMode,
+		ReadOnly:        o.ReadOnly,
+		LockTimeout:     o.LockTimeout,
+		VerifyChecksums: o.VerifyChecksums,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if st.GetRootAddress() == store.NilAddress {
-		rootAddress, err := btree.CreateEmptyBTree(st, 3, 32)
-		if err != nil {
-			return nil, errors.Wrap(err, "while creating empty root btree")
-		}
+	if st.GetRootAddress() == store.NilAddress && o.ReadOnly {
+		st.Close()
+		return nil, errors.New("database has no root, it has to be opened read-write first")
+	}
 
-		err = st.SetRootAddress(rootAddress)
+	if st.GetRootAddress() == store.NilAddress {
+		err = createRoot(st, o)
 		if err != nil {
-			return nil, errors.Wrap(err, "while setting root address")
+			st.Close()
+			return nil, err
 		}
 	}
 
 	return &DB{
-		st: st,
+		st:        st,
+		options:   o,
+		writeLock: make(chan struct{}, 1),
 	}, nil
 
 }
 
+func createRoot(st *store.Store, o Options) error {
+	pm, err := st.PrivateMMap()
+	if err != nil {
+		return errors.Wrap(err, "while creating private MMAP for root creation")
+	}
+
+	rootAddress, err := btree.CreateEmptyBTree(pm, o.RootBTreeOrder, o.KeySizeHint)
+	if err != nil {
+		pm.Rollback()
+		return errors.Wrap(err, "while creating empty root btree")
+	}
+
+	err = pm.SetRootAddress(rootAddress)
+	if err != nil {
+		pm.Rollback()
+		return errors.Wrap(err, "while setting root address")
+	}
+
+	return pm.Commit()
+}
+
+// LastTxID returns the id of the last committed write transaction.
+// Ids of committed transactions are monotonically increasing, every
+// committed write transaction or direct modification increments it by one.
+func (d *DB) LastTxID() uint64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.st.TxID()
+}
+
 func (d *DB) Close() error {
 	return d.st.Close()
 }
 
-func (d *DB) NewWriteTransaction() (*WriteTransaction, error) {
-	// TODO locking, context, one write tx at a time
+// NewWriteTransaction starts a new write transaction.
+// Only one write transaction can be active at a time, NewWriteTransaction
+// blocks until the previous one is committed or rolled back, or until the
+// context is done.
+// NewWriteTransaction returns ErrReadOnly if the database is opened read-only.
+func (d *DB) NewWriteTransaction(ctx context.Context) (*WriteTransaction, error) {
+	if d.options.ReadOnly {
+		return nil, ErrReadOnly
+	}
+
+	select {
+	case d.writeLock <- struct{}{}:
+	case <-ctx.Done():
+		return nil, errors.Wrap(ctx.Err(), "while waiting for write lock")
+	}
+
 	st, err := d.st.PrivateMMap()
 	if err != nil {
-		return nil, errors.Wrap(err, "while creating private MMAP for read tx")
+		<-d.writeLock
+		return nil, errors.Wrap(err, "while creating private MMAP for write tx")
 	}
 
 	return &WriteTransaction{
-		s: st,
+		db: d,
+		s:  st,
 	}, nil
 }
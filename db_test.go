@@ -1,11 +1,16 @@
 package l5db_test
 
 import (
+	"bytes"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/draganm/l5db"
+	"github.com/draganm/l5db/store"
 	"github.com/stretchr/testify/require"
 )
 
@@ -83,3 +88,357 @@ func TestPutData(t *testing.T) {
 	require.Equal(t, []byte{1, 2, 3}, d)
 
 }
+
+func TestDelete(t *testing.T) {
+	db, cleanup := createEmptyDB(t)
+
+	defer cleanup()
+
+	err := db.CreateMap("abc")
+	require.NoError(t, err)
+
+	err = db.Put("abc/def", []byte{1, 2, 3})
+	require.NoError(t, err)
+
+	err = db.Put("ghi", []byte{4, 5, 6})
+	require.NoError(t, err)
+
+	t.Run("deleting a value", func(t *testing.T) {
+		err = db.Delete("ghi")
+		require.NoError(t, err)
+
+		ex, err := db.Exists("ghi")
+		require.NoError(t, err)
+		require.False(t, ex)
+	})
+
+	t.Run("deleting a map", func(t *testing.T) {
+		err = db.Delete("abc")
+		require.NoError(t, err)
+
+		ex, err := db.Exists("abc")
+		require.NoError(t, err)
+		require.False(t, ex)
+
+		ex, err = db.Exists("abc/def")
+		require.NoError(t, err)
+		require.False(t, ex)
+
+		rootSize, err := db.Size("")
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), rootSize)
+	})
+
+	t.Run("deleting a non existing path", func(t *testing.T) {
+		err = db.Delete("abc")
+		require.Error(t, err)
+	})
+
+}
+
+func TestOverwriteReusesSpace(t *testing.T) {
+	td, cleanup := createTempDir(t)
+	defer cleanup()
+
+	db, err := l5db.Open(td)
+	require.NoError(t, err)
+	defer db.Close()
+
+	value := make([]byte, 10*1024)
+
+	for i := 0; i < 2000; i++ {
+		value[0] = byte(i)
+		err = db.Put("abc", value)
+		require.NoError(t, err)
+	}
+
+	d, err := db.Get("abc")
+	require.NoError(t, err)
+	require.Equal(t, value, d)
+
+	st, err := os.Stat(filepath.Join(td, "db"))
+	require.NoError(t, err)
+	require.Less(t, st.Size(), int64(len(value)*2000))
+
+}
+
+func TestLongKeys(t *testing.T) {
+	db, cleanup := createEmptyDB(t)
+	defer cleanup()
+
+	for i := 0; i < 20; i++ {
+		k := fmt.Sprintf("%d%s", i, strings.Repeat("x", 1000))
+		err := db.Put(k, []byte{byte(i)})
+		require.NoError(t, err)
+	}
+
+	for i := 0; i < 20; i++ {
+		k := fmt.Sprintf("%d%s", i, strings.Repeat("x", 1000))
+		d, err := db.Get(k)
+		require.NoError(t, err)
+		require.Equal(t, []byte{byte(i)}, d)
+	}
+
+}
+
+func TestAppend(t *testing.T) {
+	db, cleanup := createEmptyDB(t)
+	defer cleanup()
+
+	err := db.CreateMap("log")
+	require.NoError(t, err)
+
+	expected := []byte{}
+
+	for i := 0; i < 1000; i++ {
+		line := []byte(fmt.Sprintf("entry %d\n", i))
+		err = db.Append("log/current", line)
+		require.NoError(t, err)
+		expected = append(expected, line...)
+	}
+
+	d, err := db.Get("log/current")
+	require.NoError(t, err)
+	require.Equal(t, expected, d)
+
+	err = db.Put("value", []byte{1, 2, 3})
+	require.NoError(t, err)
+
+	err = db.Append("value", []byte{4, 5})
+	require.NoError(t, err)
+
+	d, err = db.Get("value")
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3, 4, 5}, d)
+
+	err = db.Append("log", []byte{1})
+	require.Error(t, err)
+
+	err = db.Verify()
+	require.NoError(t, err)
+}
+
+func TestAppendToValueWithSmallBlocks(t *testing.T) {
+	td, cleanup := createTempDir(t)
+	defer cleanup()
+
+	db, err := l5db.OpenWithOptions(td, l5db.Options{SequentialBlockSize: 16})
+	require.NoError(t, err)
+
+	first := bytes.Repeat([]byte{1}, 100)
+
+	err = db.Put("abc", first)
+	require.NoError(t, err)
+
+	err = db.Close()
+	require.NoError(t, err)
+
+	db, err = l5db.Open(td)
+	require.NoError(t, err)
+
+	entries := bytes.Repeat([]byte{2}, 30000)
+
+	err = db.Append("abc", entries)
+	require.NoError(t, err)
+
+	d, err := db.Get("abc")
+	require.NoError(t, err)
+	require.Equal(t, append(first, entries...), d)
+
+	err = db.Verify()
+	require.NoError(t, err)
+
+	err = db.Close()
+	require.NoError(t, err)
+
+	st, err := store.Open(td, 1024*1024*1024)
+	require.NoError(t, err)
+	defer st.Close()
+
+	// the value is rewritten with blocks of the configured block size
+	dataBlocks := 0
+	err = st.Blocks(func(a store.Address, t store.BlockType) error {
+		if t == store.SequentialDataBlockType {
+			dataBlocks++
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.LessOrEqual(t, dataBlocks, 2)
+}
+
+func TestTruncateAndWriteAt(t *testing.T) {
+	db, cleanup := createEmptyDB(t)
+	defer cleanup()
+
+	data := []byte(strings.Repeat("0123456789", 5000))
+
+	err := db.Put("file", data)
+	require.NoError(t, err)
+
+	err = db.WriteAt("file", 16380, []byte("record"))
+	require.NoError(t, err)
+
+	err = db.Truncate("file", 20000)
+	require.NoError(t, err)
+
+	expected := append([]byte{}, data[:20000]...)
+	copy(expected[16380:], "record")
+
+	d, err := db.Get("file")
+	require.NoError(t, err)
+	require.Equal(t, expected, d)
+
+	err = db.WriteAt("file", 20000, []byte("tail"))
+	require.NoError(t, err)
+
+	d, err = db.Get("file")
+	require.NoError(t, err)
+	require.Equal(t, append(expected, "tail"...), d)
+
+	err = db.CreateMap("m")
+	require.NoError(t, err)
+
+	err = db.Truncate("m", 0)
+	require.Error(t, err)
+
+	err = db.Verify()
+	require.NoError(t, err)
+}
+
+func TestModifiedSmallValuesStayInline(t *testing.T) {
+	td, cleanup := createTempDir(t)
+	defer cleanup()
+
+	db, err := l5db.OpenWithOptions(td, l5db.Options{SequentialBlockSize: 16})
+	require.NoError(t, err)
+
+	err = db.Put("abc", []byte("01234567"))
+	require.NoError(t, err)
+
+	err = db.WriteAt("abc", 6, []byte("abcd"))
+	require.NoError(t, err)
+
+	err = db.Truncate("abc", 8)
+	require.NoError(t, err)
+
+	err = db.Append("abc", []byte("xyz"))
+	require.NoError(t, err)
+
+	err = db.WriteAt("def", 0, []byte{1})
+	require.Error(t, err)
+
+	err = db.Put("def", []byte{1, 2, 3})
+	require.NoError(t, err)
+
+	err = db.WriteAt("def", 4, []byte{1})
+	require.Error(t, err)
+
+	err = db.Truncate("def", 4)
+	require.Error(t, err)
+
+	d, err := db.Get("abc")
+	require.NoError(t, err)
+	require.Equal(t, []byte("012345abxyz"), d)
+
+	err = db.Close()
+	require.NoError(t, err)
+
+	countBlocks := func(bt store.BlockType) int {
+		st, err := store.Open(td, 1024*1024*1024)
+		require.NoError(t, err)
+		defer st.Close()
+
+		n := 0
+		err = st.Blocks(func(a store.Address, t store.BlockType) error {
+			if t == bt {
+				n++
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		return n
+	}
+
+	require.Equal(t, 0, countBlocks(store.SequentialMetaBlockType))
+	require.Equal(t, 2, countBlocks(store.SequentialInlineBlockType))
+
+	db, err = l5db.OpenWithOptions(td, l5db.Options{SequentialBlockSize: 16})
+	require.NoError(t, err)
+
+	// values that don't fit into a block anymore become sequential
+	err = db.WriteAt("abc", 11, []byte("0123456789"))
+	require.NoError(t, err)
+
+	d, err = db.Get("abc")
+	require.NoError(t, err)
+	require.Equal(t, []byte("012345abxyz0123456789"), d)
+
+	err = db.Verify()
+	require.NoError(t, err)
+
+	err = db.Close()
+	require.NoError(t, err)
+
+	require.Equal(t, 1, countBlocks(store.SequentialMetaBlockType))
+	require.Equal(t, 1, countBlocks(store.SequentialInlineBlockType))
+}
+
+func TestSmallValuesAreStoredInline(t *testing.T) {
+	td, cleanup := createTempDir(t)
+	defer cleanup()
+
+	db, err := l5db.Open(td)
+	require.NoError(t, err)
+	defer db.Close()
+
+	st, err := os.Stat(filepath.Join(td, "db"))
+	require.NoError(t, err)
+	sizeBefore := st.Size()
+
+	err = db.CreateMap("counters")
+	require.NoError(t, err)
+
+	for i := 0; i < 1000; i++ {
+		err = db.Put(fmt.Sprintf("counters/%d", i), []byte{byte(i), 2, 3})
+		require.NoError(t, err)
+	}
+
+	st, err = os.Stat(filepath.Join(td, "db"))
+	require.NoError(t, err)
+	// a value stored in a sequential meta block and a data block would
+	// take at least 64 bytes
+	require.Less(t, st.Size()-sizeBefore, int64(1000*64))
+
+	d, err := db.Get("counters/7")
+	require.NoError(t, err)
+	require.Equal(t, []byte{7, 2, 3}, d)
+
+	r, err := db.GetReader("counters/7")
+	require.NoError(t, err)
+	d, err = ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, []byte{7, 2, 3}, d)
+	err = r.Close()
+	require.NoError(t, err)
+
+	err = db.WriteAt("counters/1", 1, []byte{9, 9, 9})
+	require.NoError(t, err)
+
+	d, err = db.Get("counters/1")
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 9, 9, 9}, d)
+
+	err = db.Truncate("counters/2", 1)
+	require.NoError(t, err)
+
+	d, err = db.Get("counters/2")
+	require.NoError(t, err)
+	require.Equal(t, []byte{2}, d)
+
+	err = db.Delete("counters/3")
+	require.NoError(t, err)
+
+	err = db.Verify()
+	require.NoError(t, err)
+}
@@ -1,168 +1,130 @@
 package l5db
 
 import (
+	"context"
 	serrors "errors"
-	"io/ioutil"
 
-	"github.com/draganm/l5db/btree"
-	"github.com/draganm/l5db/dbpath"
-	"github.com/draganm/l5db/sequential"
-	"github.com/draganm/l5db/store"
 	"github.com/pkg/errors"
 )
 
 var ErrNotFound = serrors.New("not found")
 
-func (d *DB) getAddressOfParent(parsedPath []string) (store.Address, error) {
-
-	ma := d.st.GetRootAddress()
-
-	for _, pe := range parsedPath[:len(parsedPath)-1] {
-		var err error
-		ma, err = btree.Get(d.st, ma, []byte(pe))
-		if err != nil {
-			return store.NilAddress, errors.Wrap(err, "while creating map")
-		}
-	}
-
-	return ma, nil
-
-}
-
-func (d *DB) CreateMap(pth string) error {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	parsedPath, err := dbpath.Split(pth)
-	if err != nil {
-		return errors.Wrapf(err, "while parsing dbpath %q", pth)
-	}
-
-	if len(pth) == 0 {
-		return errors.New("trying to create root")
-	}
-
-	lastKey := parsedPath[len(parsedPath)-1]
-
-	ma, err := d.getAddressOfParent(parsedPath)
+// update runs fn in a new write transaction and commits it if fn succeeds.
+func (d *DB) update(fn func(tx *WriteTransaction) error) error {
+	tx, err := d.NewWriteTransaction(context.Background())
 	if err != nil {
 		return err
 	}
 
-	empty, err := btree.CreateEmptyBTree(d.st, 5, 32)
+	err = fn(tx)
 	if err != nil {
-		return errors.Wrap(err, "while creating empty btree")
+		rbErr := tx.Rollback()
+		if rbErr != nil {
+			return errors.Wrapf(err, "rollback failed: %s", rbErr)
+		}
+		return err
 	}
 
-	return btree.Put(d.st, ma, []byte(lastKey), empty)
+	return tx.Commit()
+}
 
+func (d *DB) CreateMap(pth string) error {
+	return d.update(func(tx *WriteTransaction) error {
+		return tx.CreateMap(pth)
+	})
 }
 
-func (d *DB) getAddressOf(pth string) (store.Address, error) {
-	parsedPath, err := dbpath.Split(pth)
+// view runs fn in a new read transaction.
+func (d *DB) view(fn func(tx *ReadTransaction) error) error {
+	tx, err := d.NewReadTransaction()
 	if err != nil {
-		return store.NilAddress, errors.Wrapf(err, "while parsing dbpath %q", pth)
-	}
-
-	ma := d.st.GetRootAddress()
-
-	for _, pe := range parsedPath {
-		var err error
-		ma, err = btree.Get(d.st, ma, []byte(pe))
-		if err != nil {
-			return store.NilAddress, errors.Wrap(err, "while getting element")
-		}
+		return err
 	}
 
-	return ma, nil
+	defer tx.Close()
 
+	return fn(tx)
 }
 
 func (d *DB) Size(path string) (uint64, error) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	ta, err := d.getAddressOf(path)
-	if err != nil {
-		return 0, err
-	}
-
-	return btree.Count(d.st, ta)
+	var s uint64
+	err := d.view(func(tx *ReadTransaction) (err error) {
+		s, err = tx.Size(path)
+		return err
+	})
+	return s, err
 }
 
 func (d *DB) Exists(path string) (bool, error) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	a, err := d.getAddressOf(path)
-
-	cause := errors.Cause(err)
-
-	if cause == btree.ErrNotFound {
-		return false, nil
-	}
-
-	if err != nil {
-		return false, err
-	}
-
-	return a != store.NilAddress, nil
+	var ex bool
+	err := d.view(func(tx *ReadTransaction) (err error) {
+		ex, err = tx.Exists(path)
+		return err
+	})
+	return ex, err
 }
 
 func (d *DB) Put(pth string, data []byte) error {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	parsedPath, err := dbpath.Split(pth)
-	if err != nil {
-		return errors.Wrapf(err, "while parsing dbpath %q", pth)
-	}
-
-	if len(pth) == 0 {
-		return errors.New("trying to put data into root")
-	}
-
-	lastKey := parsedPath[len(parsedPath)-1]
-
-	ma, err := d.getAddressOfParent(parsedPath)
-	if err != nil {
-		return err
-	}
-
-	blockSize := 16 * 1024
+	return d.update(func(tx *WriteTransaction) error {
+		return tx.Put(pth, data)
+	})
+}
 
-	if len(data) < blockSize {
-		blockSize = len(data)
-	}
+// Append appends the data to the value at the path, creating the value if
+// it doesn't exist.
+func (d *DB) Append(pth string, data []byte) error {
+	return d.update(func(tx *WriteTransaction) error {
+		return tx.Append(pth, data)
+	})
+}
 
-	empty, err := sequential.CreateEmpty(d.st, uint16(blockSize))
-	if err != nil {
-		return errors.Wrap(err, "while creating empty sequential data")
-	}
+// Truncate shrinks the value at the path to n bytes.
+func (d *DB) Truncate(pth string, n uint64) error {
+	return d.update(func(tx *WriteTransaction) error {
+		return tx.Truncate(pth, n)
+	})
+}
 
-	err = sequential.Append(d.st, empty, data)
-	if err != nil {
-		return errors.Wrap(err, "while appending sequential data")
-	}
+// WriteAt overwrites the value at the path with the data starting at the
+// offset. Data past the end of the value is appended, the offset must not
+// be larger than the size of the value.
+func (d *DB) WriteAt(pth string, off uint64, data []byte) error {
+	return d.update(func(tx *WriteTransaction) error {
+		return tx.WriteAt(pth, off, data)
+	})
+}
 
-	return btree.Put(d.st, ma, []byte(lastKey), empty)
+func (d *DB) Delete(pth string) error {
+	return d.update(func(tx *WriteTransaction) error {
+		return tx.Delete(pth)
+	})
 }
 
 func (d *DB) Get(path string) ([]byte, error) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	a, err := d.getAddressOf(path)
-
-	if err != nil {
-		return nil, err
-	}
-
-	r, err := sequential.Reader(d.st, a)
-	if err != nil {
-		return nil, err
-	}
+	var data []byte
+	err := d.view(func(tx *ReadTransaction) (err error) {
+		data, err = tx.Get(path)
+		return err
+	})
+	return data, err
+}
 
-	return ioutil.ReadAll(r)
+// List returns names of all children of the map at the path.
+func (d *DB) List(path string) ([]ListEntry, error) {
+	var entries []ListEntry
+	err := d.view(func(tx *ReadTransaction) (err error) {
+		entries, err = tx.List(path)
+		return err
+	})
+	return entries, err
+}
 
+// Scan returns the children of the map at the path selected by the options.
+func (d *DB) Scan(path string, o ScanOptions) ([]ListEntry, error) {
+	var entries []ListEntry
+	err := d.view(func(tx *ReadTransaction) (err error) {
+		entries, err = tx.Scan(path, o)
+		return err
+	})
+	return entries, err
 }
@@ -0,0 +1,48 @@
+package l5db
+
+import (
+	"github.com/draganm/l5db/btree"
+	"github.com/draganm/l5db/sequential"
+	"github.com/draganm/l5db/store"
+	"github.com/pkg/errors"
+)
+
+// freeValue releases all blocks of a value or a map, including all maps
+// and values contained in the map.
+func freeValue(m store.Memory, a store.Address) error {
+	_, t, err := m.GetBlock(a)
+	if err != nil {
+		return errors.Wrap(err, "while getting value block")
+	}
+
+	switch t {
+	case store.BTreeMetaBlockType:
+		return btree.Free(m, a, func(v store.Address) error {
+			return freeValue(m, v)
+		})
+	case store.SequentialMetaBlockType, store.SequentialInlineBlockType:
+		return sequential.Free(m, a)
+	default:
+		return errors.Errorf("unsupported value block type %d", t)
+	}
+}
+
+// replaceValue stores the value address under the key of the map and frees
+// the value or map previously stored under the same key.
+func replaceValue(m store.Memory, ma store.Address, key []byte, value store.Address) error {
+	old, err := btree.Get(m, ma, key)
+	if err == btree.ErrNotFound {
+		return btree.Put(m, ma, key, value)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = btree.Put(m, ma, key, value)
+	if err != nil {
+		return err
+	}
+
+	return freeValue(m, old)
+}
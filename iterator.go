@@ -0,0 +1,111 @@
+package l5db
+
+import (
+	"github.com/draganm/l5db/btree"
+	"github.com/draganm/l5db/store"
+	"github.com/pkg/errors"
+)
+
+// Iterator iterates over the children of a map in the order of their names.
+// Iterator is only valid within the transaction that created it.
+type Iterator struct {
+	m store.Memory
+	c *btree.Cursor
+}
+
+// ListEntry is a child of a map.
+type ListEntry struct {
+	Name  string
+	IsMap bool
+}
+
+func newIterator(m store.Memory, root store.Address, path string) (*Iterator, error) {
+	a, err := getMapAddress(m, root, path)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := btree.NewCursor(m, a)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while creating cursor for %q", path)
+	}
+
+	return &Iterator{
+		m: m,
+		c: c,
+	}, nil
+}
+
+func list(m store.Memory, root store.Address, path string) ([]ListEntry, error) {
+	it, err := newIterator(m, root, path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []ListEntry{}
+
+	for err = it.First(); it.Valid(); err = it.Next() {
+		if err != nil {
+			return nil, err
+		}
+
+		isMap, err := it.IsMap()
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, ListEntry{
+			Name:  it.Name(),
+			IsMap: isMap,
+		})
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// First positions the iterator at the first child.
+func (i *Iterator) First() error {
+	return i.c.First()
+}
+
+// Last positions the iterator at the last child.
+func (i *Iterator) Last() error {
+	return i.c.Last()
+}
+
+// Seek positions the iterator at the first child with name greater or equal
+// to the name.
+func (i *Iterator) Seek(name string) error {
+	return i.c.Seek([]byte(name))
+}
+
+func (i *Iterator) Next() error {
+	return i.c.Next()
+}
+
+func (i *Iterator) Prev() error {
+	return i.c.Prev()
+}
+
+// Valid returns true if the iterator is positioned at a child.
+func (i *Iterator) Valid() bool {
+	return i.c.Valid()
+}
+
+func (i *Iterator) Name() string {
+	return string(i.c.Key())
+}
+
+// IsMap returns true if the current child is a map, false if it is a value.
+func (i *Iterator) IsMap() (bool, error) {
+	isMap, err := isMapAddress(i.m, i.c.Value())
+	if err != nil {
+		return false, errors.Wrapf(err, "while getting block of %q", i.Name())
+	}
+
+	return isMap, nil
+}
@@ -0,0 +1,76 @@
+package l5db_test
+
+import (
+	"testing"
+
+	"github.com/draganm/l5db"
+	"github.com/stretchr/testify/require"
+)
+
+func TestList(t *testing.T) {
+	db, cleanup := createEmptyDB(t)
+	defer cleanup()
+
+	err := db.CreateMap("abc")
+	require.NoError(t, err)
+
+	err = db.Put("abc/foo", []byte{1})
+	require.NoError(t, err)
+
+	err = db.CreateMap("abc/bar")
+	require.NoError(t, err)
+
+	err = db.Put("abc/baz", []byte{2})
+	require.NoError(t, err)
+
+	entries, err := db.List("abc")
+	require.NoError(t, err)
+	require.Equal(t, []l5db.ListEntry{
+		{Name: "bar", IsMap: true},
+		{Name: "baz", IsMap: false},
+		{Name: "foo", IsMap: false},
+	}, entries)
+
+	entries, err = db.List("abc/bar")
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	_, err = db.List("abc/foo")
+	require.Error(t, err)
+
+}
+
+func TestIterator(t *testing.T) {
+	db, cleanup := createEmptyDB(t)
+	defer cleanup()
+
+	for _, n := range []string{"a", "c", "e"} {
+		err := db.Put(n, []byte(n))
+		require.NoError(t, err)
+	}
+
+	tx, err := db.NewReadTransaction()
+	require.NoError(t, err)
+	defer tx.Close()
+
+	it, err := tx.Iterator("")
+	require.NoError(t, err)
+
+	names := []string{}
+	for err = it.Last(); it.Valid(); err = it.Prev() {
+		require.NoError(t, err)
+		names = append(names, it.Name())
+	}
+	require.NoError(t, err)
+	require.Equal(t, []string{"e", "c", "a"}, names)
+
+	err = it.Seek("b")
+	require.NoError(t, err)
+	require.True(t, it.Valid())
+	require.Equal(t, "c", it.Name())
+
+	isMap, err := it.IsMap()
+	require.NoError(t, err)
+	require.False(t, isMap)
+
+}
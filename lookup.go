@@ -0,0 +1,126 @@
+package l5db
+
+import (
+	"io/ioutil"
+
+	"github.com/draganm/l5db/btree"
+	"github.com/draganm/l5db/dbpath"
+	"github.com/draganm/l5db/sequential"
+	"github.com/draganm/l5db/store"
+	"github.com/pkg/errors"
+)
+
+func getAddressOfParent(m store.Memory, root store.Address, parsedPath []string) (store.Address, error) {
+
+	ma := root
+
+	for _, pe := range parsedPath[:len(parsedPath)-1] {
+		var err error
+		ma, err = btree.Get(m, ma, []byte(pe))
+		if err != nil {
+			return store.NilAddress, errors.Wrap(err, "while creating map")
+		}
+	}
+
+	return ma, nil
+
+}
+
+func getAddressOf(m store.Memory, root store.Address, pth string) (store.Address, error) {
+	parsedPath, err := dbpath.Split(pth)
+	if err != nil {
+		return store.NilAddress, errors.Wrapf(err, "while parsing dbpath %q", pth)
+	}
+
+	ma := root
+
+	for _, pe := range parsedPath {
+		var err error
+		ma, err = btree.Get(m, ma, []byte(pe))
+		if err != nil {
+			return store.NilAddress, errors.Wrap(err, "while getting element")
+		}
+	}
+
+	return ma, nil
+
+}
+
+// getMapAddress returns the address of the map at the path, or an error if
+// the path is a value.
+func getMapAddress(m store.Memory, root store.Address, pth string) (store.Address, error) {
+	a, err := getAddressOf(m, root, pth)
+	if err != nil {
+		return store.NilAddress, err
+	}
+
+	isMap, err := isMapAddress(m, a)
+	if err != nil {
+		return store.NilAddress, errors.Wrapf(err, "while getting block of %q", pth)
+	}
+
+	if !isMap {
+		return store.NilAddress, errors.Errorf("%q is not a map", pth)
+	}
+
+	return a, nil
+}
+
+func isMapAddress(m store.Memory, a store.Address) (bool, error) {
+	_, t, err := m.GetBlock(a)
+	if err != nil {
+		return false, err
+	}
+
+	return t == store.BTreeMetaBlockType, nil
+}
+
+func size(m store.Memory, root store.Address, path string) (uint64, error) {
+
+	ta, err := getAddressOf(m, root, path)
+	if err != nil {
+		return 0, err
+	}
+
+	return btree.Count(m, ta)
+}
+
+func exists(m store.Memory, root store.Address, path string) (bool, error) {
+
+	a, err := getAddressOf(m, root, path)
+
+	cause := errors.Cause(err)
+
+	if cause == btree.ErrNotFound {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return a != store.NilAddress, nil
+}
+
+func get(m store.Memory, root store.Address, path string) ([]byte, error) {
+
+	r, err := getReader(m, root, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(r)
+
+}
+
+func getReader(m store.Memory, root store.Address, path string) (ValueReader, error) {
+
+	a, err := getAddressOf(m, root, path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return sequential.Reader(m, a)
+
+}
@@ -0,0 +1,105 @@
+package l5db
+
+import (
+	"math"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Options configure the database opened with OpenWithOptions.
+// Zero values are replaced with the defaults.
+type Options struct {
+	// MaxMapSize is the size of the memory mapped region and the maximum
+	// size of the database file. Defaults to 1 TiB.
+	MaxMapSize int
+	// GrowthIncrement is the number of bytes the database file is extended
+	// by when it runs out of space. Defaults to 16 MiB.
+	GrowthIncrement uint64
+	// RootBTreeOrder is the order (t) of the root map's btree, used only when
+	// a new database is created. Defaults to 3.
+	RootBTreeOrder byte
+	// BTreeOrder is the order (t) of btrees of newly created maps.
+	// Defaults to 5.
+	BTreeOrder byte
+	// KeySizeHint is the expected size of keys, used to size btree nodes of
+	// newly created maps. Defaults to 32.
+	KeySizeHint uint16
+	// SequentialBlockSize is the maximum data block size of values.
+	// Defaults to 16 KiB.
+	SequentialBlockSize uint16
+	// FileMode is used when creating database files. Defaults to 0600.
+	FileMode os.FileMode
+	// ReadOnly opens an existing database without the possibility to
+	// modify it. Write transactions and direct modifications return
+	// ErrReadOnly.
+	ReadOnly bool
+	// LockTimeout is how long opening waits for a database that is locked
+	// by another process before returning ErrLocked. Writers lock the
+	// database exclusively, read-only opens share the lock.
+	// Zero fails immediately.
+	LockTimeout time.Duration
+	// VerifyChecksums verifies the checksum of every block read from the
+	// database, reads of corrupted blocks return ErrCorrupt.
+	VerifyChecksums bool
+}
+
+// DefaultOptions are used by Open.
+var DefaultOptions = Options{
+	MaxMapSize:          1 * 1024 * 1024 * 1024 * 1024,
+	GrowthIncrement:     16 * 1024 * 1024,
+	RootBTreeOrder:      3,
+	BTreeOrder:          5,
+	KeySizeHint:         32,
+	SequentialBlockSize: 16 * 1024,
+	FileMode:            0600,
+}
+
+// maxBTreeOrder keeps the number of keys of a btree node (2t-1) within
+// the one byte key count.
+const maxBTreeOrder = (math.MaxUint8 + 1) / 2
+
+func (o Options) withDefaults() Options {
+	d := DefaultOptions
+
+	if o.MaxMapSize == 0 {
+		o.MaxMapSize = d.MaxMapSize
+	}
+
+	if o.GrowthIncrement == 0 {
+		o.GrowthIncrement = d.GrowthIncrement
+	}
+
+	if o.RootBTreeOrder == 0 {
+		o.RootBTreeOrder = d.RootBTreeOrder
+	}
+
+	if o.BTreeOrder == 0 {
+		o.BTreeOrder = d.BTreeOrder
+	}
+
+	if o.KeySizeHint == 0 {
+		o.KeySizeHint = d.KeySizeHint
+	}
+
+	if o.SequentialBlockSize == 0 {
+		o.SequentialBlockSize = d.SequentialBlockSize
+	}
+
+	if o.FileMode == 0 {
+		o.FileMode = d.FileMode
+	}
+
+	return o
+}
+
+func (o Options) validate() error {
+	for _, t := range []byte{o.RootBTreeOrder, o.BTreeOrder} {
+		if t < 2 || t > maxBTreeOrder {
+			return errors.Errorf("btree order must be between 2 and %d, got %d", maxBTreeOrder, t)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package l5db_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/draganm/l5db"
+	"github.com/draganm/l5db/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenWithOptions(t *testing.T) {
+	td, cleanup := createTempDir(t)
+	defer cleanup()
+
+	db, err := l5db.OpenWithOptions(td, l5db.Options{
+		MaxMapSize:          64 * 1024 * 1024,
+		GrowthIncrement:     64 * 1024,
+		BTreeOrder:          2,
+		KeySizeHint:         8,
+		SequentialBlockSize: 4,
+		FileMode:            0640,
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.CreateMap("abc")
+	require.NoError(t, err)
+
+	data := []byte("some data spanning multiple blocks")
+
+	for _, k := range []string{"a", "b", "c", "d", "e", "f"} {
+		err = db.Put("abc/"+k, data)
+		require.NoError(t, err)
+	}
+
+	d, err := db.Get("abc/f")
+	require.NoError(t, err)
+	require.Equal(t, data, d)
+
+	fi, err := os.Stat(filepath.Join(td, "db"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0640), fi.Mode().Perm())
+	require.Equal(t, int64(0), (fi.Size()-4096)%(64*1024))
+	require.Less(t, fi.Size(), int64(1024*1024))
+
+}
+
+func TestOpenWithInvalidOptions(t *testing.T) {
+	td, cleanup := createTempDir(t)
+	defer cleanup()
+
+	_, err := l5db.OpenWithOptions(td, l5db.Options{
+		BTreeOrder: 1,
+	})
+	require.Error(t, err)
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	td, cleanup := createTempDir(t)
+	defer cleanup()
+
+	_, err := l5db.OpenWithOptions(td, l5db.Options{ReadOnly: true})
+	require.Error(t, err)
+
+	_, err = os.Stat(filepath.Join(td, "db"))
+	require.True(t, os.IsNotExist(err))
+
+	db, err := l5db.Open(td)
+	require.NoError(t, err)
+
+	err = db.Put("abc", []byte{1, 2, 3})
+	require.NoError(t, err)
+
+	err = db.Close()
+	require.NoError(t, err)
+
+	db, err = l5db.OpenWithOptions(td, l5db.Options{ReadOnly: true})
+	require.NoError(t, err)
+	defer db.Close()
+
+	d, err := db.Get("abc")
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, d)
+
+	err = db.Put("def", []byte{4})
+	require.True(t, errors.Is(err, l5db.ErrReadOnly))
+
+	err = db.Delete("abc")
+	require.True(t, errors.Is(err, l5db.ErrReadOnly))
+
+	err = db.CreateMap("m")
+	require.True(t, errors.Is(err, l5db.ErrReadOnly))
+
+	_, err = db.NewWriteTransaction(context.Background())
+	require.True(t, errors.Is(err, l5db.ErrReadOnly))
+	require.True(t, errors.Is(err, store.ErrReadOnly))
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	td, cleanup := createTempDir(t)
+	defer cleanup()
+
+	db, err := l5db.Open(td)
+	require.NoError(t, err)
+
+	value := []byte("value that is going to be corrupted")
+
+	err = db.Put("abc", value)
+	require.NoError(t, err)
+
+	err = db.Close()
+	require.NoError(t, err)
+
+	fileName := filepath.Join(td, "db")
+	d, err := ioutil.ReadFile(fileName)
+	require.NoError(t, err)
+
+	idx := bytes.Index(d, value)
+	require.True(t, idx > 0)
+	d[idx] = 'V'
+
+	err = ioutil.WriteFile(fileName, d, 0600)
+	require.NoError(t, err)
+
+	db, err = l5db.OpenWithOptions(td, l5db.Options{VerifyChecksums: true})
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Get("abc")
+	require.True(t, errors.Is(err, l5db.ErrCorrupt))
+}
+
+func TestPutLargerThanMaxMapSize(t *testing.T) {
+	td, cleanup := createTempDir(t)
+	defer cleanup()
+
+	db, err := l5db.OpenWithOptions(td, l5db.Options{MaxMapSize: 1024 * 1024})
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Put("abc", make([]byte, 1024*1024))
+	require.True(t, errors.Is(err, l5db.ErrFull))
+
+	err = db.PutReader("abc", bytes.NewReader(make([]byte, 1024*1024)))
+	require.True(t, errors.Is(err, l5db.ErrFull))
+	require.NotContains(t, err.Error(), "abort failed")
+
+	tx, err := db.NewWriteTransaction(context.Background())
+	require.NoError(t, err)
+
+	err = tx.PutReader("abc", bytes.NewReader(make([]byte, 1024*1024)))
+	require.True(t, errors.Is(err, l5db.ErrFull))
+	require.NotContains(t, err.Error(), "abort failed")
+
+	err = tx.Rollback()
+	require.NoError(t, err)
+
+	err = db.Put("abc", []byte{1, 2, 3})
+	require.NoError(t, err)
+
+	err = db.Verify()
+	require.NoError(t, err)
+}
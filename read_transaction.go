@@ -0,0 +1,104 @@
+package l5db
+
+import (
+	"github.com/draganm/l5db/store"
+	"github.com/pkg/errors"
+)
+
+// ReadTransaction is a consistent, read-only snapshot of the database.
+// Any number of read transactions can be open at the same time, also
+// while a write transaction is in progress. Write transactions commit
+// without waiting for open read transactions, which keep reading the
+// state committed when they were started.
+type ReadTransaction struct {
+	s        *store.Store
+	root     store.Address
+	finished bool
+}
+
+// NewReadTransaction starts a new read transaction pinned to the last
+// committed state of the database.
+func (d *DB) NewReadTransaction() (*ReadTransaction, error) {
+	s, err := d.st.Snapshot()
+	if err != nil {
+		return nil, errors.Wrap(err, "while creating snapshot for read tx")
+	}
+
+	return &ReadTransaction{
+		s:    s,
+		root: s.GetRootAddress(),
+	}, nil
+}
+
+// Close ends the read transaction.
+func (r *ReadTransaction) Close() error {
+	if r.finished {
+		return ErrTransactionFinished
+	}
+
+	r.finished = true
+
+	return r.s.Close()
+}
+
+func (r *ReadTransaction) Size(path string) (uint64, error) {
+	if r.finished {
+		return 0, ErrTransactionFinished
+	}
+
+	return size(r.s, r.root, path)
+}
+
+func (r *ReadTransaction) Exists(path string) (bool, error) {
+	if r.finished {
+		return false, ErrTransactionFinished
+	}
+
+	return exists(r.s, r.root, path)
+}
+
+func (r *ReadTransaction) Get(path string) ([]byte, error) {
+	if r.finished {
+		return nil, ErrTransactionFinished
+	}
+
+	return get(r.s, r.root, path)
+}
+
+// GetReader returns a reader of the value at the path.
+// The reader reads directly from the database and must not be used after
+// the read transaction is closed.
+func (r *ReadTransaction) GetReader(path string) (ValueReader, error) {
+	if r.finished {
+		return nil, ErrTransactionFinished
+	}
+
+	return getReader(r.s, r.root, path)
+}
+
+// Iterator returns an iterator over the children of the map at the path.
+// The iterator must not be used after the read transaction is closed.
+func (r *ReadTransaction) Iterator(path string) (*Iterator, error) {
+	if r.finished {
+		return nil, ErrTransactionFinished
+	}
+
+	return newIterator(r.s, r.root, path)
+}
+
+func (r *ReadTransaction) List(path string) ([]ListEntry, error) {
+	if r.finished {
+		return nil, ErrTransactionFinished
+	}
+
+	return list(r.s, r.root, path)
+}
+
+// Scan returns the children of the map at the path selected by the options.
+func (r *ReadTransaction) Scan(path string, o ScanOptions) ([]ListEntry, error) {
+	if r.finished {
+		return nil, ErrTransactionFinished
+	}
+
+	return scan(r.s, r.root, path, o)
+}
@@ -0,0 +1,152 @@
+package l5db_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/draganm/l5db"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTransactionSnapshot(t *testing.T) {
+	db, cleanup := createEmptyDB(t)
+	defer cleanup()
+
+	err := db.Put("abc", []byte{1, 2, 3})
+	require.NoError(t, err)
+
+	wtx, err := db.NewWriteTransaction(context.Background())
+	require.NoError(t, err)
+
+	err = wtx.Put("abc", []byte{4, 5, 6})
+	require.NoError(t, err)
+
+	rtx1, err := db.NewReadTransaction()
+	require.NoError(t, err)
+
+	rtx2, err := db.NewReadTransaction()
+	require.NoError(t, err)
+
+	d, err := rtx1.Get("abc")
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, d)
+
+	d, err = rtx2.Get("abc")
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, d)
+
+	err = wtx.Commit()
+	require.NoError(t, err)
+
+	d, err = rtx1.Get("abc")
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, d)
+
+	err = rtx1.Close()
+	require.NoError(t, err)
+
+	_, err = rtx1.Get("abc")
+	require.True(t, errors.Is(err, l5db.ErrTransactionFinished))
+
+	// the value is overwritten in place by the next commit
+	err = db.Put("abc", []byte{7, 8, 9})
+	require.NoError(t, err)
+
+	d, err = rtx2.Get("abc")
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, d)
+
+	err = rtx2.Close()
+	require.NoError(t, err)
+
+	rtx3, err := db.NewReadTransaction()
+	require.NoError(t, err)
+	defer rtx3.Close()
+
+	d, err = rtx3.Get("abc")
+	require.NoError(t, err)
+	require.Equal(t, []byte{7, 8, 9}, d)
+
+	sz, err := rtx3.Size("")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), sz)
+
+}
+
+func TestWriteWhileHoldingReadTransaction(t *testing.T) {
+	db, cleanup := createEmptyDB(t)
+	defer cleanup()
+
+	err := db.Put("abc", []byte{1, 2, 3})
+	require.NoError(t, err)
+
+	rtx, err := db.NewReadTransaction()
+	require.NoError(t, err)
+	defer rtx.Close()
+
+	r, err := db.GetReader("abc")
+	require.NoError(t, err)
+	defer r.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; i < 10; i++ {
+			err := db.Put("abc", []byte{byte(i)})
+			if err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	select {
+	case err = <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "writes are blocked by open read transactions")
+	}
+
+	d, err := rtx.Get("abc")
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, d)
+
+	d = make([]byte, 3)
+	_, err = io.ReadFull(r, d)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, d)
+
+	d, err = db.Get("abc")
+	require.NoError(t, err)
+	require.Equal(t, []byte{9}, d)
+}
+
+func TestHoldManyReadTransactionsAcrossCommits(t *testing.T) {
+	db, cleanup := createEmptyDB(t)
+	defer cleanup()
+
+	txs := []*l5db.ReadTransaction{}
+	defer func() {
+		for _, rtx := range txs {
+			rtx.Close()
+		}
+	}()
+
+	for i := 0; i < 300; i++ {
+		err := db.Put("abc", []byte{byte(i)})
+		require.NoError(t, err)
+
+		rtx, err := db.NewReadTransaction()
+		require.NoError(t, err)
+		txs = append(txs, rtx)
+	}
+
+	for i, rtx := range txs {
+		d, err := rtx.Get("abc")
+		require.NoError(t, err)
+		require.Equal(t, []byte{byte(i)}, d)
+	}
+}
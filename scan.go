@@ -0,0 +1,71 @@
+package l5db
+
+import (
+	"github.com/draganm/l5db/btree"
+	"github.com/draganm/l5db/store"
+	"github.com/pkg/errors"
+)
+
+// ScanOptions selects the children of a map returned by Scan.
+type ScanOptions struct {
+	// Start is the smallest name included, empty for no lower bound.
+	Start string
+	// End is the first name not included, empty for no upper bound.
+	End string
+	// Prefix restricts the scan to names starting with the prefix.
+	Prefix string
+	// Reverse returns the children in the reverse order of their names.
+	Reverse bool
+	// Limit is the maximum number of returned children, 0 for no limit.
+	Limit int
+}
+
+func (o ScanOptions) toRange() btree.Range {
+	r := btree.Range{}
+
+	if o.Prefix != "" {
+		r = btree.PrefixRange([]byte(o.Prefix))
+	}
+
+	if o.Start != "" && (r.Start == nil || o.Start > string(r.Start)) {
+		r.Start = []byte(o.Start)
+	}
+
+	if o.End != "" && (r.End == nil || o.End < string(r.End)) {
+		r.End = []byte(o.End)
+	}
+
+	r.Reverse = o.Reverse
+	r.Limit = o.Limit
+
+	return r
+}
+
+func scan(m store.Memory, root store.Address, path string, o ScanOptions) ([]ListEntry, error) {
+	ma, err := getMapAddress(m, root, path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []ListEntry{}
+
+	err = btree.Scan(m, ma, o.toRange(), func(key []byte, value store.Address) error {
+		isMap, err := isMapAddress(m, value)
+		if err != nil {
+			return errors.Wrapf(err, "while getting block of %q", string(key))
+		}
+
+		entries = append(entries, ListEntry{
+			Name:  string(key),
+			IsMap: isMap,
+		})
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,80 @@
+package l5db_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/draganm/l5db"
+	"github.com/draganm/l5db/dbpath"
+	"github.com/stretchr/testify/require"
+)
+
+func entryNames(entries []l5db.ListEntry) []string {
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+func TestScan(t *testing.T) {
+	db, cleanup := createEmptyDB(t)
+	defer cleanup()
+
+	err := db.CreateMap("idx")
+	require.NoError(t, err)
+
+	for _, n := range []string{"user/1", "user/2", "user/3", "group/1", "zzz"} {
+		err = db.Put("idx/"+dbpath.EscapePart(n), []byte(n))
+		require.NoError(t, err)
+	}
+
+	cases := []struct {
+		title    string
+		o        l5db.ScanOptions
+		expected []string
+	}{
+		{
+			title:    "range",
+			o:        l5db.ScanOptions{Start: "group/1", End: "user/3"},
+			expected: []string{"group/1", "user/1", "user/2"},
+		},
+		{
+			title:    "prefix",
+			o:        l5db.ScanOptions{Prefix: "user/"},
+			expected: []string{"user/1", "user/2", "user/3"},
+		},
+		{
+			title:    "prefix reverse with limit",
+			o:        l5db.ScanOptions{Prefix: "user/", Reverse: true, Limit: 2},
+			expected: []string{"user/3", "user/2"},
+		},
+		{
+			title:    "prefix and start",
+			o:        l5db.ScanOptions{Prefix: "user/", Start: "user/2"},
+			expected: []string{"user/2", "user/3"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.title, func(t *testing.T) {
+			entries, err := db.Scan("idx", c.o)
+			require.NoError(t, err)
+			require.Equal(t, c.expected, entryNames(entries))
+		})
+	}
+
+	t.Run("scan in write transaction", func(t *testing.T) {
+		tx, err := db.NewWriteTransaction(context.Background())
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		err = tx.Put("idx/"+dbpath.EscapePart("user/0"), []byte{1})
+		require.NoError(t, err)
+
+		entries, err := tx.Scan("idx", l5db.ScanOptions{Prefix: "user/", Limit: 1})
+		require.NoError(t, err)
+		require.Equal(t, []l5db.ListEntry{{Name: "user/0", IsMap: false}}, entries)
+	})
+
+}
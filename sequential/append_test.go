@@ -114,3 +114,21 @@ func TestAppendTreeBlocks(t *testing.T) {
 	require.Equal(t, uint64(3), sz)
 
 }
+
+func TestAppendLast(t *testing.T) {
+	ts, cleanup := createTestStore(t)
+	defer cleanup()
+
+	a, err := sequential.CreateEmpty(ts, 1024)
+	require.NoError(t, err)
+
+	data := testData(3000)
+
+	err = sequential.AppendLast(ts, a, data[:2500])
+	require.NoError(t, err)
+	requireData(t, ts, a, data[:2500])
+
+	err = sequential.Append(ts, a, data[2500:])
+	require.NoError(t, err)
+	requireData(t, ts, a, data)
+}
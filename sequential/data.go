@@ -44,6 +44,15 @@ func getData(m store.Memory, a store.Address) (data, error) {
 		return data{}, errors.New("not a sequential data block type")
 	}
 
+	if len(bl) < dataHeaderSize {
+		return data{}, errors.Wrapf(store.ErrCorrupt, "sequential data block at %d has less than %d bytes", a, dataHeaderSize)
+	}
+
+	size := int(binary.LittleEndian.Uint16(bl[8:]))
+	if size > len(bl)-dataHeaderSize {
+		return data{}, errors.Wrapf(store.ErrCorrupt, "data of %d bytes doesn't fit into sequential data block at %d", size, a)
+	}
+
 	return data{
 		m:    m,
 		addr: a,
@@ -81,6 +90,11 @@ func (d data) dataSize() uint16 {
 	return binary.LittleEndian.Uint16(d.bl[8:])
 }
 
+func (d data) setDataSize(size uint16) error {
+	binary.LittleEndian.PutUint16(d.bl[8:], size)
+	return d.m.Touch(d.addr)
+}
+
 func (d data) increaseDataSize(delta uint16) error {
 	nds := d.dataSize() + delta
 	binary.LittleEndian.PutUint16(d.bl[8:], nds)
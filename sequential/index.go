@@ -0,0 +1,94 @@
+package sequential
+
+import (
+	"encoding/binary"
+
+	"github.com/draganm/l5db/btree"
+	"github.com/draganm/l5db/store"
+	"github.com/pkg/errors"
+)
+
+// The index of a sequential value is a btree mapping the offset of the
+// first byte of each data block (big endian, so keys are in offset order)
+// to the address of the data block.
+// It is created when the second data block is appended, values with a
+// single data block have no index.
+
+const indexBTreeOrder = 16
+const indexKeySize = 8
+
+func offsetKey(offset uint64) []byte {
+	k := make([]byte, indexKeySize)
+	binary.BigEndian.PutUint64(k, offset)
+	return k
+}
+
+func (m meta) index() store.Address {
+	return store.Address(binary.LittleEndian.Uint64(m.bl[26:]))
+}
+
+func (m meta) setIndex(a store.Address) error {
+	binary.LittleEndian.PutUint64(m.bl[26:], a.UInt64())
+	return m.m.Touch(m.addr)
+}
+
+// indexDataBlock adds the data block starting at the offset to the index,
+// creating the index if needed.
+func (m meta) indexDataBlock(offset uint64, a store.Address) error {
+	if m.index() == store.NilAddress {
+		idx, err := btree.CreateEmptyBTree(m.m, indexBTreeOrder, indexKeySize)
+		if err != nil {
+			return errors.Wrap(err, "while creating index")
+		}
+
+		err = btree.Put(m.m, idx, offsetKey(0), m.firstDataBlockAddress())
+		if err != nil {
+			return errors.Wrap(err, "while indexing first data block")
+		}
+
+		err = m.setIndex(idx)
+		if err != nil {
+			return err
+		}
+	}
+
+	return btree.Put(m.m, m.index(), offsetKey(offset), a)
+}
+
+// findDataBlock returns the data block containing the byte at the offset
+// and the offset of the first byte of the data block.
+func (m meta) findDataBlock(offset uint64) (data, uint64, error) {
+	if m.index() == store.NilAddress {
+		d, err := m.getFirstDataBlock()
+		return d, 0, err
+	}
+
+	var start uint64
+	found := store.NilAddress
+
+	r := btree.Range{
+		End:     offsetKey(offset + 1),
+		Reverse: true,
+		Limit:   1,
+	}
+
+	err := btree.Scan(m.m, m.index(), r, func(key []byte, value store.Address) error {
+		start = binary.BigEndian.Uint64(key)
+		found = value
+		return nil
+	})
+	if err != nil {
+		return data{}, 0, errors.Wrap(err, "while searching index")
+	}
+
+	if found == store.NilAddress {
+		return data{}, 0, errors.Errorf("index has no data block for offset %d", offset)
+	}
+
+	d, err := getData(m.m, found)
+	if err != nil {
+		return data{}, 0, err
+	}
+
+	return d, start, nil
+}
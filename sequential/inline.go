@@ -0,0 +1,77 @@
+package sequential
+
+import (
+	"encoding/binary"
+	serrors "errors"
+	"math"
+
+	"github.com/draganm/l5db/store"
+	"github.com/pkg/errors"
+)
+
+// inline value layout:
+// 2 bytes - data size
+// data size bytes: data
+
+const inlineHeaderSize = 2
+
+// MaxInlineSize is the maximum size of data stored in an inline value.
+const MaxInlineSize = math.MaxUint16
+
+var ErrInline = serrors.New("inline values can't be modified")
+
+// CreateInline stores the data in a single block.
+// Inline values are read like other sequential values, but they can't be
+// modified.
+func CreateInline(m store.Memory, d []byte) (store.Address, error) {
+	if len(d) > MaxInlineSize {
+		return store.NilAddress, errors.Errorf("inline value can have at most %d bytes, got %d", MaxInlineSize, len(d))
+	}
+
+	a, bl, err := m.Allocate(inlineHeaderSize+len(d), store.SequentialInlineBlockType)
+	if err != nil {
+		return store.NilAddress, errors.Wrap(err, "while allocating inline value")
+	}
+
+	binary.LittleEndian.PutUint16(bl, uint16(len(d)))
+	copy(bl[inlineHeaderSize:], d)
+
+	err = m.Touch(a)
+	if err != nil {
+		return store.NilAddress, err
+	}
+
+	return a, nil
+}
+
+// IsInline returns true if the value at the address is an inline value.
+func IsInline(m store.Memory, a store.Address) (bool, error) {
+	_, t, err := m.GetBlock(a)
+	if err != nil {
+		return false, err
+	}
+
+	return t == store.SequentialInlineBlockType, nil
+}
+
+func getInline(m store.Memory, a store.Address) ([]byte, error) {
+	bl, t, err := m.GetBlock(a)
+	if err != nil {
+		return nil, errors.Wrap(err, "while getting inline value block")
+	}
+
+	if t != store.SequentialInlineBlockType {
+		return nil, errors.New("block is not inline value block")
+	}
+
+	if len(bl) < inlineHeaderSize {
+		return nil, errors.Wrapf(store.ErrCorrupt, "inline value block at %d is too small", a)
+	}
+
+	size := int(binary.LittleEndian.Uint16(bl))
+	if size > len(bl)-inlineHeaderSize {
+		return nil, errors.Wrapf(store.ErrCorrupt, "inline value of %d bytes doesn't fit into its block at %d", size, a)
+	}
+
+	return bl[inlineHeaderSize : inlineHeaderSize+size], nil
+}
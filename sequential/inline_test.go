@@ -0,0 +1,60 @@
+package sequential_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/draganm/l5db/sequential"
+	"github.com/draganm/l5db/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInline(t *testing.T) {
+	ts, cleanup := createTestStore(t)
+	defer cleanup()
+
+	data := testData(100)
+
+	a, err := sequential.CreateInline(ts, data)
+	require.NoError(t, err)
+
+	isInline, err := sequential.IsInline(ts, a)
+	require.NoError(t, err)
+	require.True(t, isInline)
+
+	requireData(t, ts, a, data)
+
+	size, err := sequential.Size(ts, a)
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), size)
+
+	r, err := sequential.Reader(ts, a)
+	require.NoError(t, err)
+
+	_, err = r.Seek(90, io.SeekStart)
+	require.NoError(t, err)
+
+	buf := make([]byte, 20)
+	n, err := r.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, data[90:], buf[:n])
+
+	n, err = r.ReadAt(buf[:5], 10)
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+	require.Equal(t, data[10:15], buf[:5])
+
+	err = sequential.Append(ts, a, []byte{1})
+	require.True(t, errors.Is(err, sequential.ErrInline))
+
+	err = sequential.Free(ts, a)
+	require.NoError(t, err)
+
+	_, bt, err := ts.GetBlock(a)
+	require.NoError(t, err)
+	require.Equal(t, store.FreeBlockType, bt)
+
+	_, err = sequential.CreateInline(ts, make([]byte, sequential.MaxInlineSize+1))
+	require.Error(t, err)
+}
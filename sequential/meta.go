@@ -3,6 +3,7 @@ package sequential
 import (
 	"encoding/binary"
 
+	"github.com/draganm/l5db/btree"
 	"github.com/draganm/l5db/store"
 	"github.com/pkg/errors"
 )
@@ -12,6 +13,7 @@ import (
 // 8 bytes - address of the last data block
 // 8 bytes - data size
 // 2 bytes - data block size
+// 8 bytes - address of the index of data blocks
 
 type meta struct {
 	m    store.Memory
@@ -19,7 +21,7 @@ type meta struct {
 	bl   []byte
 }
 
-const metaSize = 26
+const metaSize = 34
 
 func createMeta(m store.Memory, blockSize uint16) (store.Address, meta, error) {
 	a, d, err := m.Allocate(metaSize, store.SequentialMetaBlockType)
@@ -44,12 +46,16 @@ func getMeta(m store.Memory, a store.Address) (meta, error) {
 		return meta{}, errors.Wrap(err, "while getting sequential meta block")
 	}
 
+	if t == store.SequentialInlineBlockType {
+		return meta{}, ErrInline
+	}
+
 	if t != store.SequentialMetaBlockType {
-		return meta{}, errors.Wrap(err, "block is not sequential meta block")
+		return meta{}, errors.New("block is not sequential meta block")
 	}
 
 	if len(b) < metaSize {
-		return meta{}, errors.Wrapf(err, "store sequential meta block is less than %d bytes", metaSize)
+		return meta{}, errors.Wrapf(store.ErrCorrupt, "sequential meta block at %d has less than %d bytes", a, metaSize)
 	}
 
 	return meta{
@@ -64,8 +70,22 @@ func (m meta) dataSize() uint64 {
 }
 
 func (m meta) append(data []byte) error {
+	return m.appendData(data, false)
+}
+
+// appendData appends the data, creating data blocks of the block size.
+// If tight is set, a data block created for less than the block size of
+// remaining data is sized to fit it.
+func (m meta) appendData(data []byte, tight bool) error {
+	newBlockSize := func(remaining int) uint16 {
+		if tight && remaining < int(m.blockSize()) {
+			return uint16(remaining)
+		}
+		return m.blockSize()
+	}
+
 	if m.isEmpty() {
-		err := m.createFirstDataBlock()
+		err := m.createFirstDataBlock(newBlockSize(len(data)))
 		if err != nil {
 			return errors.Wrap(err, "while creating first data block")
 		}
@@ -86,7 +106,7 @@ func (m meta) append(data []byte) error {
 
 		data = data[cnt:]
 		if len(data) > 0 {
-			err = m.appendEmptyBlock()
+			err = m.appendEmptyBlock(m.dataSize()+uint64(totalSize-len(data)), newBlockSize(len(data)))
 			if err != nil {
 				return err
 			}
@@ -98,13 +118,15 @@ func (m meta) append(data []byte) error {
 
 }
 
-func (m meta) appendEmptyBlock() error {
+// appendEmptyBlock appends an empty data block of the size that will hold
+// the data starting at the offset.
+func (m meta) appendEmptyBlock(offset uint64, size uint16) error {
 	ldb, err := getData(m.m, m.lastDataBlockAddress())
 	if err != nil {
 		return err
 	}
 
-	ndba, _, err := createEmptyData(m.m, m.blockSize())
+	ndba, _, err := createEmptyData(m.m, size)
 	if err != nil {
 		return err
 	}
@@ -114,6 +136,11 @@ func (m meta) appendEmptyBlock() error {
 		return errors.Wrap(err, "while setting the next block address")
 	}
 
+	err = m.indexDataBlock(offset, ndba)
+	if err != nil {
+		return errors.Wrap(err, "while indexing data block")
+	}
+
 	return m.setLastDataBlock(ndba)
 }
 
@@ -160,8 +187,8 @@ func (m meta) setLastDataBlock(a store.Address) error {
 	return m.m.Touch(m.addr)
 }
 
-func (m meta) createFirstDataBlock() error {
-	a, _, err := createEmptyData(m.m, m.blockSize())
+func (m meta) createFirstDataBlock(size uint16) error {
+	a, _, err := createEmptyData(m.m, size)
 	if err != nil {
 		return errors.Wrap(err, "while creating first data block")
 	}
@@ -180,13 +207,34 @@ func (m meta) createFirstDataBlock() error {
 
 }
 
-func (m meta) reader() (*reader, error) {
-	fdb, err := m.getFirstDataBlock()
-	if err != nil {
-		return nil, err
+func (m meta) free() error {
+	da := m.firstDataBlockAddress()
+
+	for da != store.NilAddress {
+		d, err := getData(m.m, da)
+		if err != nil {
+			return errors.Wrap(err, "while getting data block")
+		}
+
+		next := d.nextBlockAddress()
+
+		err = m.m.Free(da)
+		if err != nil {
+			return errors.Wrap(err, "while freeing data block")
+		}
+
+		da = next
 	}
 
-	return &reader{
-		d: fdb,
-	}, nil
+	if m.index() != store.NilAddress {
+		err := btree.Free(m.m, m.index(), func(store.Address) error {
+			// data blocks are already freed
+			return nil
+		})
+		if err != nil {
+			return errors.Wrap(err, "while freeing index")
+		}
+	}
+
+	return m.m.Free(m.addr)
 }
@@ -6,36 +6,150 @@ import (
 	"github.com/pkg/errors"
 )
 
-type reader struct {
-	d   data
-	pos int
+// ValueReader reads the data of a sequential value.
+// Besides reading the data in order, it supports random access with ReadAt
+// and Seek, which use the index of the value to find the data block
+// containing an offset.
+type ValueReader struct {
+	met meta
+	// inline is the data of an inline value, valid if isInline is set
+	inline   []byte
+	isInline bool
+	// d is the data block containing the byte at pos, valid if hasBlock is set
+	d        data
+	dOffset  uint64
+	hasBlock bool
+	pos      uint64
 }
 
-func (r *reader) Read(p []byte) (n int, err error) {
-	payload := r.d.payload()[r.pos:]
+// locate makes d the data block containing the byte at pos.
+func (r *ValueReader) locate() error {
+	if !r.hasBlock || r.pos < r.dOffset || r.pos > r.dOffset+uint64(r.d.dataSize()) {
+		d, offset, err := r.met.findDataBlock(r.pos)
+		if err != nil {
+			return err
+		}
+
+		r.d = d
+		r.dOffset = offset
+		r.hasBlock = true
+	}
 
-	if len(payload) == 0 {
+	for r.pos >= r.dOffset+uint64(r.d.dataSize()) {
 		if !r.d.hasNextBlock() {
-			return 0, io.EOF
+			return errors.Errorf("data ends before offset %d", r.pos)
 		}
-		bl, err := r.d.nextBlock()
+
+		next, err := r.d.nextBlock()
 		if err != nil {
-			return 0, errors.Wrap(err, "while getting next block")
+			return errors.Wrap(err, "while getting next block")
 		}
-		r.d = bl
-		r.pos = 0
 
-		payload = r.d.payload()[r.pos:]
+		r.dOffset += uint64(r.d.dataSize())
+		r.d = next
+	}
+
+	return nil
+}
+
+func (r *ValueReader) size() uint64 {
+	if r.isInline {
+		return uint64(len(r.inline))
+	}
+	return r.met.dataSize()
+}
+
+func (r *ValueReader) Read(p []byte) (int, error) {
+	if r.pos >= r.size() {
+		return 0, io.EOF
+	}
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if r.isInline {
+		n := copy(p, r.inline[r.pos:])
+		r.pos += uint64(n)
+		return n, nil
+	}
+
+	err := r.locate()
+	if err != nil {
+		return 0, err
+	}
+
+	n := copy(p, r.d.payload()[r.pos-r.dOffset:])
+	r.pos += uint64(n)
+
+	return n, nil
+}
+
+// ReadAt reads len(p) bytes starting at the offset, it doesn't change the
+// position of the reader.
+func (r *ValueReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
 	}
 
-	toDo := len(payload)
+	c := *r
+	c.pos = uint64(off)
 
-	if len(p) < len(payload) {
-		toDo = len(p)
+	n, err := io.ReadFull(&c, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
 	}
 
-	copy(p, payload)
-	r.pos += toDo
-	return toDo, nil
+	return n, err
+}
+
+func (r *ValueReader) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = int64(r.pos) + offset
+	case io.SeekEnd:
+		abs = int64(r.size()) + offset
+	default:
+		return 0, errors.Errorf("invalid whence %d", whence)
+	}
+
+	if abs < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	r.pos = uint64(abs)
+
+	return abs, nil
+}
+
+// WriteTo writes the rest of the data to w, block by block directly from
+// the memory of the store.
+func (r *ValueReader) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+
+	if r.isInline && r.pos < r.size() {
+		n, err := w.Write(r.inline[r.pos:])
+		r.pos += uint64(n)
+		return int64(n), err
+	}
+
+	for r.pos < r.size() {
+		err := r.locate()
+		if err != nil {
+			return written, err
+		}
+
+		n, err := w.Write(r.d.payload()[r.pos-r.dOffset:])
+		written += int64(n)
+		r.pos += uint64(n)
+		if err != nil {
+			return written, err
+		}
+	}
 
+	return written, nil
 }
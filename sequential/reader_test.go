@@ -0,0 +1,100 @@
+package sequential_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/draganm/l5db/sequential"
+	"github.com/draganm/l5db/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReaderWriteTo(t *testing.T) {
+	ts, cleanup := createTestStore(t)
+	defer cleanup()
+
+	a, err := sequential.CreateEmpty(ts, 7)
+	require.NoError(t, err)
+
+	data := bytes.Repeat([]byte{1, 2, 3}, 100)
+
+	err = sequential.Append(ts, a, data)
+	require.NoError(t, err)
+
+	r, err := sequential.Reader(ts, a)
+	require.NoError(t, err)
+
+	// read part of the first block before writing the rest
+	p := make([]byte, 5)
+	_, err = io.ReadFull(r, p)
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	n, err := r.WriteTo(buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(data)-5), n)
+	require.Equal(t, data[5:], buf.Bytes())
+}
+
+func TestReaderRandomAccess(t *testing.T) {
+	ts, cleanup := createTestStore(t)
+	defer cleanup()
+
+	a, err := sequential.CreateEmpty(ts, 16)
+	require.NoError(t, err)
+
+	data := make([]byte, 10000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	// append in pieces not aligned to block boundaries
+	for i := 0; i < len(data); i += 333 {
+		end := i + 333
+		if end > len(data) {
+			end = len(data)
+		}
+		err = sequential.Append(ts, a, data[i:end])
+		require.NoError(t, err)
+	}
+
+	err = sequential.Verify(ts, a, func(store.Address) error { return nil })
+	require.NoError(t, err)
+
+	r, err := sequential.Reader(ts, a)
+	require.NoError(t, err)
+
+	for _, off := range []int{0, 1, 15, 16, 17, 5000, 9990} {
+		p := make([]byte, 10)
+		n, err := r.ReadAt(p, int64(off))
+		require.NoError(t, err)
+		require.Equal(t, 10, n)
+		require.Equal(t, data[off:off+10], p)
+	}
+
+	p := make([]byte, 10)
+	n, err := r.ReadAt(p, 9995)
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, 5, n)
+	require.Equal(t, data[9995:], p[:n])
+
+	pos, err := r.Seek(-100, io.SeekEnd)
+	require.NoError(t, err)
+	require.Equal(t, int64(9900), pos)
+
+	rest, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, data[9900:], rest)
+
+	_, err = r.Seek(1234, io.SeekStart)
+	require.NoError(t, err)
+
+	_, err = r.Seek(10, io.SeekCurrent)
+	require.NoError(t, err)
+
+	rest, err = ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, data[1244:], rest)
+}
@@ -1,14 +1,24 @@
 package sequential
 
 import (
-	"bytes"
-	"io"
-
 	"github.com/draganm/l5db/store"
 	"github.com/pkg/errors"
 )
 
 func Size(m store.Memory, a store.Address) (uint64, error) {
+	isInline, err := IsInline(m, a)
+	if err != nil {
+		return 0, err
+	}
+
+	if isInline {
+		d, err := getInline(m, a)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(len(d)), nil
+	}
+
 	met, err := getMeta(m, a)
 	if err != nil {
 		return 0, err
@@ -17,6 +27,16 @@ func Size(m store.Memory, a store.Address) (uint64, error) {
 	return met.dataSize(), nil
 }
 
+// BlockSize returns the maximum number of bytes stored in one data block.
+func BlockSize(m store.Memory, a store.Address) (uint16, error) {
+	met, err := getMeta(m, a)
+	if err != nil {
+		return 0, err
+	}
+
+	return met.blockSize(), nil
+}
+
 func Append(m store.Memory, a store.Address, data []byte) error {
 	met, err := getMeta(m, a)
 	if err != nil {
@@ -27,6 +47,18 @@ func Append(m store.Memory, a store.Address, data []byte) error {
 
 }
 
+// AppendLast appends the data like Append, but a data block created for
+// the end of the data is sized to fit it instead of the block size of the
+// value. Following appends create data blocks of the block size again.
+func AppendLast(m store.Memory, a store.Address, data []byte) error {
+	met, err := getMeta(m, a)
+	if err != nil {
+		return err
+	}
+
+	return met.appendData(data, true)
+}
+
 func CreateEmpty(m store.Memory, blockSize uint16) (store.Address, error) {
 	a, _, err := createMeta(m, blockSize)
 	if err != nil {
@@ -37,16 +69,50 @@ func CreateEmpty(m store.Memory, blockSize uint16) (store.Address, error) {
 
 }
 
-func Reader(m store.Memory, a store.Address) (io.Reader, error) {
+// Reader returns a reader of the data of the sequential value.
+func Reader(m store.Memory, a store.Address) (*ValueReader, error) {
+	isInline, err := IsInline(m, a)
+	if err != nil {
+		return nil, err
+	}
+
+	if isInline {
+		d, err := getInline(m, a)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ValueReader{
+			inline:   d,
+			isInline: true,
+		}, nil
+	}
+
 	met, err := getMeta(m, a)
 	if err != nil {
 		return nil, err
 	}
 
-	if met.isEmpty() {
-		return bytes.NewReader(nil), nil
+	return &ValueReader{
+		met: met,
+	}, nil
+}
+
+// Free releases all data blocks and the meta block of the sequential data.
+func Free(m store.Memory, a store.Address) error {
+	isInline, err := IsInline(m, a)
+	if err != nil {
+		return err
+	}
+
+	if isInline {
+		return m.Free(a)
 	}
 
-	return met.reader()
+	met, err := getMeta(m, a)
+	if err != nil {
+		return err
+	}
 
+	return met.free()
 }
@@ -0,0 +1,223 @@
+package sequential
+
+import (
+	"encoding/binary"
+
+	"github.com/draganm/l5db/btree"
+	"github.com/draganm/l5db/store"
+	"github.com/pkg/errors"
+)
+
+// Truncate shrinks the sequential value to size bytes and releases the
+// data blocks that are not needed anymore.
+func Truncate(m store.Memory, a store.Address, size uint64) error {
+	met, err := getMeta(m, a)
+	if err != nil {
+		return err
+	}
+
+	return met.truncate(size)
+}
+
+// WriteAt overwrites the data of the sequential value starting at the
+// offset. Data past the current end of the value is appended.
+// The offset must not be larger than the size of the value.
+func WriteAt(m store.Memory, a store.Address, offset uint64, data []byte) error {
+	met, err := getMeta(m, a)
+	if err != nil {
+		return err
+	}
+
+	return met.writeAt(offset, data)
+}
+
+// dataBlockAt returns the data block containing the byte at the offset and
+// the offset of the first byte of the data block.
+func (m meta) dataBlockAt(offset uint64) (data, uint64, error) {
+	d, start, err := m.findDataBlock(offset)
+	if err != nil {
+		return data{}, 0, err
+	}
+
+	for offset >= start+uint64(d.dataSize()) {
+		start += uint64(d.dataSize())
+		d, err = d.nextBlock()
+		if err != nil {
+			return data{}, 0, errors.Wrapf(err, "while looking for data block of offset %d", offset)
+		}
+	}
+
+	return d, start, nil
+}
+
+func (m meta) truncate(size uint64) error {
+	if size > m.dataSize() {
+		return errors.Errorf("can't truncate data of size %d to larger size %d", m.dataSize(), size)
+	}
+
+	if size == m.dataSize() {
+		return nil
+	}
+
+	if size == 0 {
+		err := m.freeIndex()
+		if err != nil {
+			return err
+		}
+
+		err = m.freeDataBlocks(m.firstDataBlockAddress(), 0)
+		if err != nil {
+			return err
+		}
+
+		err = m.setFirstDataBlock(store.NilAddress)
+		if err != nil {
+			return err
+		}
+
+		err = m.setLastDataBlock(store.NilAddress)
+		if err != nil {
+			return err
+		}
+
+		return m.setDataSize(0)
+	}
+
+	last, start, err := m.dataBlockAt(size - 1)
+	if err != nil {
+		return err
+	}
+
+	if start == 0 {
+		// a single data block doesn't need an index
+		err = m.freeIndex()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = m.freeDataBlocks(last.nextBlockAddress(), start+uint64(last.dataSize()))
+	if err != nil {
+		return err
+	}
+
+	err = last.setNextBlockAddress(store.NilAddress)
+	if err != nil {
+		return err
+	}
+
+	err = last.setDataSize(uint16(size - start))
+	if err != nil {
+		return err
+	}
+
+	err = m.setLastDataBlock(last.addr)
+	if err != nil {
+		return err
+	}
+
+	return m.setDataSize(size)
+}
+
+// freeDataBlocks frees the chain of data blocks starting with the block at
+// the address, which starts at the offset, and removes them from the index.
+func (m meta) freeDataBlocks(da store.Address, offset uint64) error {
+	for da != store.NilAddress {
+		d, err := getData(m.m, da)
+		if err != nil {
+			return errors.Wrap(err, "while getting data block")
+		}
+
+		next := d.nextBlockAddress()
+
+		if m.index() != store.NilAddress {
+			err = btree.Delete(m.m, m.index(), offsetKey(offset))
+			if err != nil {
+				return errors.Wrapf(err, "while removing data block at offset %d from index", offset)
+			}
+		}
+
+		offset += uint64(d.dataSize())
+
+		err = m.m.Free(da)
+		if err != nil {
+			return errors.Wrap(err, "while freeing data block")
+		}
+
+		da = next
+	}
+
+	return nil
+}
+
+func (m meta) freeIndex() error {
+	if m.index() == store.NilAddress {
+		return nil
+	}
+
+	err := btree.Free(m.m, m.index(), func(store.Address) error {
+		// data blocks are not owned by the index
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "while freeing index")
+	}
+
+	return m.setIndex(store.NilAddress)
+}
+
+func (m meta) setDataSize(size uint64) error {
+	binary.LittleEndian.PutUint64(m.bl[16:], size)
+	return m.m.Touch(m.addr)
+}
+
+func (m meta) writeAt(offset uint64, p []byte) error {
+	size := m.dataSize()
+
+	if offset > size {
+		return errors.Errorf("offset %d is past the end of data of size %d", offset, size)
+	}
+
+	inPlace := p
+	var rest []byte
+
+	if uint64(len(p)) > size-offset {
+		inPlace = p[:size-offset]
+		rest = p[size-offset:]
+	}
+
+	if len(inPlace) > 0 {
+		d, start, err := m.dataBlockAt(offset)
+		if err != nil {
+			return err
+		}
+
+		for {
+			n := copy(d.payload()[offset-start:], inPlace)
+
+			err = m.m.Touch(d.addr)
+			if err != nil {
+				return err
+			}
+
+			inPlace = inPlace[n:]
+			if len(inPlace) == 0 {
+				break
+			}
+
+			offset += uint64(n)
+			start += uint64(d.dataSize())
+
+			d, err = d.nextBlock()
+			if err != nil {
+				return errors.Wrap(err, "while getting next block")
+			}
+		}
+	}
+
+	if len(rest) == 0 {
+		return nil
+	}
+
+	return m.append(rest)
+}
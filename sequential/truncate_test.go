@@ -0,0 +1,102 @@
+package sequential_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/draganm/l5db/sequential"
+	"github.com/draganm/l5db/store"
+	"github.com/stretchr/testify/require"
+)
+
+func requireData(t *testing.T, m store.Memory, a store.Address, expected []byte) {
+	visited := map[store.Address]bool{}
+	err := sequential.Verify(m, a, func(ba store.Address) error {
+		require.False(t, visited[ba], "block %d visited twice", ba)
+		visited[ba] = true
+		return nil
+	})
+	require.NoError(t, err)
+
+	r, err := sequential.Reader(m, a)
+	require.NoError(t, err)
+
+	d, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, expected, d)
+}
+
+func testData(n int) []byte {
+	d := make([]byte, n)
+	for i := range d {
+		d[i] = byte(i * 13)
+	}
+	return d
+}
+
+func TestTruncate(t *testing.T) {
+	ts, cleanup := createTestStore(t)
+	defer cleanup()
+
+	a, err := sequential.CreateEmpty(ts, 16)
+	require.NoError(t, err)
+
+	data := testData(1000)
+
+	err = sequential.Append(ts, a, data)
+	require.NoError(t, err)
+
+	err = sequential.Truncate(ts, a, 500)
+	require.NoError(t, err)
+	requireData(t, ts, a, data[:500])
+
+	err = sequential.Truncate(ts, a, 5)
+	require.NoError(t, err)
+	requireData(t, ts, a, data[:5])
+
+	err = sequential.Append(ts, a, data[5:100])
+	require.NoError(t, err)
+	requireData(t, ts, a, data[:100])
+
+	err = sequential.Truncate(ts, a, 0)
+	require.NoError(t, err)
+	requireData(t, ts, a, []byte{})
+
+	err = sequential.Append(ts, a, data)
+	require.NoError(t, err)
+	requireData(t, ts, a, data)
+
+	err = sequential.Truncate(ts, a, 1001)
+	require.Error(t, err)
+}
+
+func TestWriteAt(t *testing.T) {
+	ts, cleanup := createTestStore(t)
+	defer cleanup()
+
+	a, err := sequential.CreateEmpty(ts, 16)
+	require.NoError(t, err)
+
+	data := testData(1000)
+
+	err = sequential.Append(ts, a, data)
+	require.NoError(t, err)
+
+	patch := []byte("overwritten across several data blocks")
+
+	err = sequential.WriteAt(ts, a, 10, patch)
+	require.NoError(t, err)
+
+	expected := append([]byte{}, data...)
+	copy(expected[10:], patch)
+	requireData(t, ts, a, expected)
+
+	err = sequential.WriteAt(ts, a, 990, patch)
+	require.NoError(t, err)
+
+	expected = append(expected[:990], patch...)
+	requireData(t, ts, a, expected)
+
+	err = sequential.WriteAt(ts, a, uint64(len(expected)+1), patch)
+	require.Error(t, err)
+}
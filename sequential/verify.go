@@ -0,0 +1,108 @@
+package sequential
+
+import (
+	"encoding/binary"
+
+	"github.com/draganm/l5db/btree"
+	"github.com/draganm/l5db/store"
+	"github.com/pkg/errors"
+)
+
+// Verify checks the chain of data blocks of the sequential value: block
+// types, the address of the last data block, the size of the value
+// against the sizes of data blocks and the index of data blocks.
+// visitBlock is called for every block of the value, an error returned by
+// it stops the verification.
+func Verify(m store.Memory, a store.Address, visitBlock func(store.Address) error) error {
+	err := visitBlock(a)
+	if err != nil {
+		return err
+	}
+
+	isInline, err := IsInline(m, a)
+	if err != nil {
+		return err
+	}
+
+	if isInline {
+		_, err = getInline(m, a)
+		return err
+	}
+
+	met, err := getMeta(m, a)
+	if err != nil {
+		return err
+	}
+
+	var total uint64
+	last := store.NilAddress
+
+	// offsets of the first byte of each data block
+	offsets := map[uint64]store.Address{}
+
+	for da := met.firstDataBlockAddress(); da != store.NilAddress; {
+		err = visitBlock(da)
+		if err != nil {
+			return err
+		}
+
+		d, err := getData(m, da)
+		if err != nil {
+			return errors.Wrapf(err, "while getting data block %d", da)
+		}
+
+		if len(d.bl) < dataHeaderSize || int(d.dataSize()) > len(d.bl)-dataHeaderSize {
+			return errors.Errorf("data block %d has size %d larger than its capacity", da, d.dataSize())
+		}
+
+		offsets[total] = da
+		total += uint64(d.dataSize())
+		last = da
+		da = d.nextBlockAddress()
+	}
+
+	if last != met.lastDataBlockAddress() {
+		return errors.Errorf("sequential value %d has last data block %d, but the chain ends with %d", a, met.lastDataBlockAddress(), last)
+	}
+
+	if total != met.dataSize() {
+		return errors.Errorf("sequential value %d has size %d, but data blocks contain %d bytes", a, met.dataSize(), total)
+	}
+
+	return verifyIndex(met, offsets, visitBlock)
+}
+
+func verifyIndex(met meta, offsets map[uint64]store.Address, visitBlock func(store.Address) error) error {
+	if met.index() == store.NilAddress {
+		if len(offsets) > 1 {
+			return errors.Errorf("sequential value %d has %d data blocks, but no index", met.addr, len(offsets))
+		}
+		return nil
+	}
+
+	indexed := 0
+
+	err := btree.Verify(met.m, met.index(), visitBlock, func(key []byte, value store.Address) error {
+		if len(key) != indexKeySize {
+			return errors.Errorf("index of sequential value %d has key of %d bytes", met.addr, len(key))
+		}
+
+		offset := binary.BigEndian.Uint64(key)
+		if offsets[offset] != value {
+			return errors.Errorf("index of sequential value %d maps offset %d to %d instead of %d", met.addr, offset, value, offsets[offset])
+		}
+
+		indexed++
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if indexed != len(offsets) {
+		return errors.Errorf("index of sequential value %d has %d entries, but there are %d data blocks", met.addr, indexed, len(offsets))
+	}
+
+	return nil
+}
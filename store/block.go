@@ -0,0 +1,74 @@
+package store
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+
+	"github.com/draganm/mmap-go"
+)
+
+// block layout:
+//  1 byte - size class, the block is 1<<size class bytes large
+//  1 byte - block type
+//  4 bytes - CRC32C of the size class, the block type and the payload
+//  payload
+//
+// The address of a block is the address of its payload.
+// The checksum is updated when the block is sealed, which happens when a
+// transaction is committed or, for stores that are modified directly,
+// when the block is touched.
+
+const blockHeaderSize = 6
+const blockTypeOffset = 1
+const blockChecksumOffset = 2
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+func blockStart(addr Address) uint64 {
+	return addr.UInt64() - blockHeaderSize
+}
+
+// blockMemory returns the memory map containing the block starting at the
+// offset.
+// Blocks past the next free address of the committed state are not
+// reachable by readers of the store, so a private memory map writes them
+// directly to the shared memory map of its parent instead of keeping
+// copies of all their pages in memory until commit.
+func (s *Store) blockMemory(start uint64) mmap.MMap {
+	if s.parent != nil && start >= s.sharedFrom {
+		return s.parent.mm
+	}
+	return s.mm
+}
+
+func (s *Store) blockChecksum(start, end uint64) uint32 {
+	mm := s.blockMemory(start)
+	c := crc32.Checksum(mm[start:start+blockChecksumOffset], castagnoli)
+	return crc32.Update(c, castagnoli, mm[start+blockHeaderSize:end])
+}
+
+func (s *Store) seal(r blockRange) {
+	binary.LittleEndian.PutUint32(s.blockMemory(r.start)[r.start+blockChecksumOffset:], s.blockChecksum(r.start, r.end))
+}
+
+// verifyChecksum compares the checksum stored in the block header with
+// the checksum of the block's content.
+// Blocks changed in a private memory map are not sealed until commit and
+// are not verified.
+func (s *Store) verifyChecksum(addr Address, bits int) error {
+	if _, isDirty := s.dirty[addr]; isDirty {
+		return nil
+	}
+
+	start := blockStart(addr)
+	end := start + uint64(1)<<bits
+
+	expected := binary.LittleEndian.Uint32(s.blockMemory(start)[start+blockChecksumOffset:])
+	actual := s.blockChecksum(start, end)
+
+	if expected != actual {
+		return corruptBlock(addr, "checksum mismatch, expected %08x, got %08x", expected, actual)
+	}
+
+	return nil
+}
@@ -7,3 +7,9 @@ const BTreeInternalNodeBlockType BlockType = 2
 const BTreeLeafBlockType BlockType = 3
 const SequentialMetaBlockType BlockType = 4
 const SequentialDataBlockType BlockType = 5
+const FreeBlockType BlockType = 6
+const SequentialInlineBlockType BlockType = 7
+
+func (t BlockType) isKnown() bool {
+	return t >= BTreeMetaBlockType && t <= SequentialInlineBlockType
+}
@@ -0,0 +1,210 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func openTestStore(t *testing.T) (string, *Store, func()) {
+	td, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+
+	st, err := Open(td, 1024*1024*1024)
+	require.NoError(t, err)
+
+	return td, st, func() {
+		os.RemoveAll(td)
+	}
+}
+
+// commitValue allocates a block containing the data in a committed transaction
+// and makes it the root.
+func commitValue(t *testing.T, st *Store, data []byte) Address {
+	pm, err := st.PrivateMMap()
+	require.NoError(t, err)
+
+	a, d, err := pm.Allocate(len(data), BTreeMetaBlockType)
+	require.NoError(t, err)
+	copy(d, data)
+
+	err = pm.Touch(a)
+	require.NoError(t, err)
+
+	err = pm.SetRootAddress(a)
+	require.NoError(t, err)
+
+	err = pm.Commit()
+	require.NoError(t, err)
+
+	return a
+}
+
+// crashAfterJournal overwrites the block in a transaction that is journaled,
+// but never applied to the store.
+func crashAfterJournal(t *testing.T, st *Store, a Address, data []byte) {
+	pm, err := st.PrivateMMap()
+	require.NoError(t, err)
+
+	bl, _, err := pm.GetBlock(a)
+	require.NoError(t, err)
+	copy(bl, data)
+
+	err = pm.Touch(a)
+	require.NoError(t, err)
+
+	pm.setTxID(st.TxID() + 1)
+
+	journaled, err := pm.journalCommittedBlocks(pm.dirtyBlockRanges())
+	require.NoError(t, err)
+	require.True(t, journaled)
+
+	err = pm.unmapPrivate()
+	require.NoError(t, err)
+
+	err = st.Close()
+	require.NoError(t, err)
+}
+
+func getBlockData(t *testing.T, st *Store, a Address) []byte {
+	bl, _, err := st.GetBlock(a)
+	require.NoError(t, err)
+	return bl[:3]
+}
+
+func TestCommitPersistsState(t *testing.T) {
+	td, st, cleanup := openTestStore(t)
+	defer cleanup()
+
+	a := commitValue(t, st, []byte{1, 2, 3})
+	require.Equal(t, uint64(1), st.TxID())
+
+	b := commitValue(t, st, []byte{4, 5, 6})
+	require.Equal(t, uint64(2), st.TxID())
+
+	err := st.Close()
+	require.NoError(t, err)
+
+	st, err = Open(td, 1024*1024*1024)
+	require.NoError(t, err)
+	defer st.Close()
+
+	require.Equal(t, uint64(2), st.TxID())
+	require.Equal(t, b, st.GetRootAddress())
+	require.Equal(t, []byte{1, 2, 3}, getBlockData(t, st, a))
+	require.Equal(t, []byte{4, 5, 6}, getBlockData(t, st, b))
+}
+
+func TestTornStateFallsBackToPreviousState(t *testing.T) {
+	td, st, cleanup := openTestStore(t)
+	defer cleanup()
+
+	a := commitValue(t, st, []byte{1, 2, 3})
+	commitValue(t, st, []byte{4, 5, 6})
+
+	current := st.stateOffset
+
+	err := st.Close()
+	require.NoError(t, err)
+
+	fileName := filepath.Join(td, "db")
+	d, err := ioutil.ReadFile(fileName)
+	require.NoError(t, err)
+
+	// simulate a torn write of the current state
+	d[current+stateRootAddressOffset]++
+
+	err = ioutil.WriteFile(fileName, d, 0600)
+	require.NoError(t, err)
+
+	st, err = Open(td, 1024*1024*1024)
+	require.NoError(t, err)
+	defer st.Close()
+
+	require.Equal(t, uint64(1), st.TxID())
+	require.Equal(t, a, st.GetRootAddress())
+}
+
+func TestJournalRecovery(t *testing.T) {
+
+	t.Run("complete journal is replayed", func(t *testing.T) {
+		td, st, cleanup := openTestStore(t)
+		defer cleanup()
+
+		a := commitValue(t, st, []byte{1, 2, 3})
+
+		crashAfterJournal(t, st, a, []byte{4, 5, 6})
+
+		st, err := Open(td, 1024*1024*1024)
+		require.NoError(t, err)
+		defer st.Close()
+
+		require.Equal(t, uint64(2), st.TxID())
+		require.Equal(t, []byte{4, 5, 6}, getBlockData(t, st, a))
+
+		fi, err := os.Stat(filepath.Join(td, journalFileName))
+		require.NoError(t, err)
+		require.Equal(t, int64(0), fi.Size())
+	})
+
+	t.Run("incomplete journal is ignored", func(t *testing.T) {
+		td, st, cleanup := openTestStore(t)
+		defer cleanup()
+
+		a := commitValue(t, st, []byte{1, 2, 3})
+
+		crashAfterJournal(t, st, a, []byte{4, 5, 6})
+
+		journalFile := filepath.Join(td, journalFileName)
+		fi, err := os.Stat(journalFile)
+		require.NoError(t, err)
+
+		err = os.Truncate(journalFile, fi.Size()-1)
+		require.NoError(t, err)
+
+		st, err = Open(td, 1024*1024*1024)
+		require.NoError(t, err)
+		defer st.Close()
+
+		require.Equal(t, uint64(1), st.TxID())
+		require.Equal(t, []byte{1, 2, 3}, getBlockData(t, st, a))
+	})
+
+}
+
+func TestFailedCommitUnmapsPrivateMap(t *testing.T) {
+	_, st, cleanup := openTestStore(t)
+	defer cleanup()
+
+	a := commitValue(t, st, []byte{1, 2, 3})
+
+	pm, err := st.PrivateMMap()
+	require.NoError(t, err)
+
+	bl, _, err := pm.GetBlock(a)
+	require.NoError(t, err)
+	copy(bl, []byte{4, 5, 6})
+
+	err = pm.Touch(a)
+	require.NoError(t, err)
+
+	// writing the journal fails
+	err = st.journal.Close()
+	require.NoError(t, err)
+
+	err = pm.Commit()
+	require.Error(t, err)
+	require.Nil(t, pm.dirty)
+
+	require.Equal(t, uint64(1), st.TxID())
+	require.Equal(t, []byte{1, 2, 3}, getBlockData(t, st, a))
+
+	pm, err = st.PrivateMMap()
+	require.NoError(t, err)
+
+	err = pm.Rollback()
+	require.NoError(t, err)
+}
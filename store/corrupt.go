@@ -0,0 +1,63 @@
+package store
+
+import (
+	serrors "errors"
+	"fmt"
+)
+
+var ErrCorrupt = serrors.New("store is corrupt")
+
+// CorruptBlockError is returned when a block can't be accessed because its
+// address or header is invalid. It matches ErrCorrupt with errors.Is.
+type CorruptBlockError struct {
+	Address Address
+	Reason  string
+}
+
+func (e *CorruptBlockError) Error() string {
+	return fmt.Sprintf("corrupt block at address %d: %s", e.Address, e.Reason)
+}
+
+func (e *CorruptBlockError) Is(target error) bool {
+	return target == ErrCorrupt
+}
+
+func corruptBlock(addr Address, format string, args ...interface{}) error {
+	return &CorruptBlockError{
+		Address: addr,
+		Reason:  fmt.Sprintf(format, args...),
+	}
+}
+
+// checkBlock validates the address and the header of the block against the
+// size of the store and returns the size class and the type of the block.
+func (s *Store) checkBlock(addr Address) (int, BlockType, error) {
+	if addr < headerSize+blockHeaderSize {
+		return 0, 0, corruptBlock(addr, "address is within the header")
+	}
+
+	nfa := s.nextFreeAddress()
+	if addr >= nfa {
+		return 0, 0, corruptBlock(addr, "address is past the highest allocated address %d", nfa)
+	}
+
+	start := blockStart(addr)
+	mm := s.blockMemory(start)
+	bits := int(mm[start])
+
+	if bits < minBlockBits || bits >= sizeClasses {
+		return 0, 0, corruptBlock(addr, "invalid block size class %d", bits)
+	}
+
+	end := start + uint64(1)<<bits
+	if end > nfa.UInt64() || end > s.currentSize {
+		return 0, 0, corruptBlock(addr, "block of %d bytes does not fit into the store", uint64(1)<<bits)
+	}
+
+	t := BlockType(mm[start+blockTypeOffset])
+	if !t.isKnown() {
+		return 0, 0, corruptBlock(addr, "unknown block type %d", t)
+	}
+
+	return bits, t, nil
+}
@@ -0,0 +1,194 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	serrors "errors"
+	"hash/crc32"
+
+	"github.com/pkg/errors"
+)
+
+// header layout (first page of the file):
+// superblock:
+//  8 bytes - magic
+//  2 bytes - format major version
+//  2 bytes - format minor version
+//  4 bytes - page size (size of the header)
+//  4 bytes - CRC32 of the preceding superblock bytes
+// two state slots (at stateSlotOffsets), each:
+//  8 bytes - transaction id
+//  8 bytes - next free address
+//  8 bytes - root address
+//  64 * 8 bytes - address of the first free block for each block size class
+//  4 bytes - CRC32 of the preceding state bytes
+//
+// The valid state slot with the higher transaction id is the current state.
+// Committing a transaction writes the new state into the other slot, so a
+// torn write of the state leaves the previous state intact.
+
+var magic = []byte{'l', '5', 'd', 'b', 0x0d, 0x0a, 0x1a, 0x0a}
+
+// FormatMajorVersion is incremented on incompatible changes of the file format.
+const FormatMajorVersion = 6
+
+// FormatMinorVersion is incremented on backwards compatible changes of the file format.
+const FormatMinorVersion = 0
+
+const pageSize = 4096
+const headerSize = pageSize
+
+const superblockChecksumOffset = 16
+
+var stateSlotOffsets = [2]uint64{1024, 2048}
+
+const stateNextFreeAddressOffset = 8
+const stateRootAddressOffset = 16
+const stateFreeListsOffset = 24
+
+// sizeClasses is the number of block size classes, block of size class
+// bits is 1<<bits bytes large.
+const sizeClasses = 64
+
+const stateChecksumOffset = stateFreeListsOffset + sizeClasses*8
+const stateSize = stateChecksumOffset + 4
+
+var ErrNotStoreFile = serrors.New("not an l5db store file")
+var ErrIncompatibleVersion = serrors.New("incompatible store format version")
+
+func newHeader() []byte {
+	h := make([]byte, headerSize)
+	copy(h, magic)
+	binary.LittleEndian.PutUint16(h[8:], FormatMajorVersion)
+	binary.LittleEndian.PutUint16(h[10:], FormatMinorVersion)
+	binary.LittleEndian.PutUint32(h[12:], pageSize)
+	binary.LittleEndian.PutUint32(h[superblockChecksumOffset:], crc32.ChecksumIEEE(h[:superblockChecksumOffset]))
+
+	st := h[stateSlotOffsets[0]:]
+	binary.LittleEndian.PutUint64(st[stateNextFreeAddressOffset:], headerSize)
+	binary.LittleEndian.PutUint32(st[stateChecksumOffset:], crc32.ChecksumIEEE(st[:stateChecksumOffset]))
+
+	return h
+}
+
+func validateHeader(h []byte) error {
+	if len(h) < headerSize || !bytes.Equal(h[:len(magic)], magic) {
+		return ErrNotStoreFile
+	}
+
+	cs := binary.LittleEndian.Uint32(h[superblockChecksumOffset:])
+	if crc32.ChecksumIEEE(h[:superblockChecksumOffset]) != cs {
+		return errors.Wrap(ErrNotStoreFile, "superblock checksum mismatch")
+	}
+
+	major := binary.LittleEndian.Uint16(h[8:])
+	minor := binary.LittleEndian.Uint16(h[10:])
+	if major != FormatMajorVersion {
+		return errors.Wrapf(ErrIncompatibleVersion, "file format version is %d.%d, supported version is %d.%d", major, minor, FormatMajorVersion, FormatMinorVersion)
+	}
+
+	ps := binary.LittleEndian.Uint32(h[12:])
+	if ps != pageSize {
+		return errors.Wrapf(ErrIncompatibleVersion, "file page size is %d, supported page size is %d", ps, pageSize)
+	}
+
+	return nil
+}
+
+func isValidState(st []byte) bool {
+	return binary.LittleEndian.Uint32(st[stateChecksumOffset:]) == crc32.ChecksumIEEE(st[:stateChecksumOffset])
+}
+
+func stateTxID(st []byte) uint64 {
+	return binary.LittleEndian.Uint64(st)
+}
+
+// currentStateSlot returns the offset of the valid state slot with the
+// highest transaction id.
+func currentStateSlot(h []byte) (uint64, error) {
+	found := false
+	var current uint64
+
+	for _, o := range stateSlotOffsets {
+		st := h[o : o+stateSize]
+		if !isValidState(st) {
+			continue
+		}
+
+		if !found || stateTxID(st) > stateTxID(h[current:]) {
+			current = o
+			found = true
+		}
+	}
+
+	if !found {
+		return 0, errors.New("no valid state slot found")
+	}
+
+	return current, nil
+}
+
+// otherStateSlot returns the offset of the state slot that is not at the
+// offset.
+func otherStateSlot(o uint64) uint64 {
+	if o == stateSlotOffsets[0] {
+		return stateSlotOffsets[1]
+	}
+	return stateSlotOffsets[0]
+}
+
+// FormatVersion returns the major and minor version of the file format.
+func (s *Store) FormatVersion() (uint16, uint16) {
+	return binary.LittleEndian.Uint16(s.mm[8:]), binary.LittleEndian.Uint16(s.mm[10:])
+}
+
+func (s *Store) state() []byte {
+	return s.mm[s.stateOffset : s.stateOffset+stateSize]
+}
+
+// updateStateChecksum has to be called after every change of the state.
+func (s *Store) updateStateChecksum() {
+	st := s.state()
+	binary.LittleEndian.PutUint32(st[stateChecksumOffset:], crc32.ChecksumIEEE(st[:stateChecksumOffset]))
+}
+
+func (s *Store) TxID() uint64 {
+	return stateTxID(s.state())
+}
+
+func (s *Store) setTxID(id uint64) {
+	binary.LittleEndian.PutUint64(s.state(), id)
+	s.updateStateChecksum()
+}
+
+func (s *Store) nextFreeAddress() Address {
+	return Address(binary.LittleEndian.Uint64(s.state()[stateNextFreeAddressOffset:]))
+}
+
+func (s *Store) setNextFreeAddress(a Address) {
+	binary.LittleEndian.PutUint64(s.state()[stateNextFreeAddressOffset:], a.UInt64())
+	s.updateStateChecksum()
+}
+
+func (s *Store) GetRootAddress() Address {
+	return Address(binary.LittleEndian.Uint64(s.state()[stateRootAddressOffset:]))
+}
+
+func (s *Store) SetRootAddress(a Address) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	binary.LittleEndian.PutUint64(s.state()[stateRootAddressOffset:], a.UInt64())
+	s.updateStateChecksum()
+	return nil
+}
+
+func (s *Store) freeListHead(bits int) Address {
+	return Address(binary.LittleEndian.Uint64(s.state()[stateFreeListsOffset+bits*8:]))
+}
+
+func (s *Store) setFreeListHead(bits int, a Address) {
+	binary.LittleEndian.PutUint64(s.state()[stateFreeListsOffset+bits*8:], a.UInt64())
+	s.updateStateChecksum()
+}
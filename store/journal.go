@@ -0,0 +1,208 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// journal layout:
+// 8 bytes - transaction id
+// 8 bytes - offset of the state slot
+// state bytes
+// 4 bytes - number of blocks
+// for every block:
+//  8 bytes - offset of the block
+//  4 bytes - length of the block
+//  block bytes
+// 4 bytes - CRC32 of all preceding bytes
+//
+// Before a commit modifies blocks that were part of the previously committed
+// state (blocks below the committed next free address), the new content of
+// those blocks and the new state are written to the journal and synced.
+// If the process crashes while the blocks are copied, the journal is replayed
+// on the next open.
+// A journal with a transaction id not higher than the one of the current
+// state has already been applied and is ignored.
+
+const journalFileName = "journal"
+
+type journalEntry struct {
+	offset uint64
+	data   []byte
+}
+
+func writeJournal(f *os.File, txID uint64, stateOffset uint64, state []byte, entries []journalEntry) error {
+	buf := new(bytes.Buffer)
+
+	b8 := make([]byte, 8)
+
+	binary.LittleEndian.PutUint64(b8, txID)
+	buf.Write(b8)
+
+	binary.LittleEndian.PutUint64(b8, stateOffset)
+	buf.Write(b8)
+
+	buf.Write(state)
+
+	binary.LittleEndian.PutUint32(b8, uint32(len(entries)))
+	buf.Write(b8[:4])
+
+	for _, e := range entries {
+		binary.LittleEndian.PutUint64(b8, e.offset)
+		buf.Write(b8)
+		binary.LittleEndian.PutUint32(b8, uint32(len(e.data)))
+		buf.Write(b8[:4])
+		buf.Write(e.data)
+	}
+
+	binary.LittleEndian.PutUint32(b8, crc32.ChecksumIEEE(buf.Bytes()))
+	buf.Write(b8[:4])
+
+	err := f.Truncate(0)
+	if err != nil {
+		return errors.Wrap(err, "while truncating journal")
+	}
+
+	_, err = f.WriteAt(buf.Bytes(), 0)
+	if err != nil {
+		return errors.Wrap(err, "while writing journal")
+	}
+
+	err = f.Sync()
+	if err != nil {
+		return errors.Wrap(err, "while syncing journal")
+	}
+
+	return nil
+}
+
+type journal struct {
+	txID        uint64
+	stateOffset uint64
+	state       []byte
+	entries     []journalEntry
+}
+
+// readJournal returns nil if the journal is empty or incomplete.
+func readJournal(f *os.File) (*journal, error) {
+	_, err := f.Seek(0, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "while seeking to the start of journal")
+	}
+
+	d, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, errors.Wrap(err, "while reading journal")
+	}
+
+	if len(d) < 16+stateSize+4+4 {
+		return nil, nil
+	}
+
+	body := d[:len(d)-4]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(d[len(d)-4:]) {
+		return nil, nil
+	}
+
+	j := &journal{
+		txID:        binary.LittleEndian.Uint64(body),
+		stateOffset: binary.LittleEndian.Uint64(body[8:]),
+		state:       body[16 : 16+stateSize],
+	}
+
+	if j.stateOffset != stateSlotOffsets[0] && j.stateOffset != stateSlotOffsets[1] {
+		return nil, errors.Errorf("journal has invalid state slot offset %d", j.stateOffset)
+	}
+
+	body = body[16+stateSize:]
+	cnt := int(binary.LittleEndian.Uint32(body))
+	body = body[4:]
+
+	for i := 0; i < cnt; i++ {
+		if len(body) < 12 {
+			return nil, errors.New("journal malformed: not enough bytes for block header")
+		}
+
+		e := journalEntry{
+			offset: binary.LittleEndian.Uint64(body),
+		}
+		l := int(binary.LittleEndian.Uint32(body[8:]))
+		body = body[12:]
+
+		if len(body) < l {
+			return nil, errors.New("journal malformed: not enough bytes for block data")
+		}
+
+		e.data = body[:l]
+		body = body[l:]
+		j.entries = append(j.entries, e)
+	}
+
+	return j, nil
+}
+
+// recoverJournal replays the journal if the process crashed while
+// committing a transaction.
+func (s *Store) recoverJournal() error {
+	j, err := readJournal(s.journal)
+	if err != nil {
+		return err
+	}
+
+	if j == nil || j.txID <= s.TxID() {
+		return s.journal.Truncate(0)
+	}
+
+	for _, e := range j.entries {
+		end := e.offset + uint64(len(e.data))
+		if e.offset < headerSize || end > s.currentSize {
+			return errors.Errorf("journal block at %d is outside of the store", e.offset)
+		}
+		copy(s.mm[e.offset:end], e.data)
+		err = s.sync(e.offset, end)
+		if err != nil {
+			return err
+		}
+	}
+
+	copy(s.mm[j.stateOffset:j.stateOffset+stateSize], j.state)
+	err = s.sync(0, headerSize)
+	if err != nil {
+		return err
+	}
+
+	s.stateOffset = j.stateOffset
+
+	return s.journal.Truncate(0)
+}
+
+// checkJournal makes sure that there is no journal that has to be replayed
+// when the store is opened read-only.
+func (s *Store) checkJournal(journalFileName string) error {
+	f, err := os.Open(journalFileName)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "while opening journal %s", journalFileName)
+	}
+
+	defer f.Close()
+
+	j, err := readJournal(f)
+	if err != nil {
+		return err
+	}
+
+	if j != nil && j.txID > s.TxID() {
+		return errors.Errorf("journal %s has to be recovered by opening the store read-write", journalFileName)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	serrors "errors"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+var ErrLocked = serrors.New("store is locked by another process")
+
+// lockRetryInterval is the time between two attempts to acquire the lock
+// when waiting for it.
+const lockRetryInterval = 10 * time.Millisecond
+
+// lockFile takes an advisory lock of the file, shared for read-only and
+// exclusive for read-write stores.
+// If the lock is held by someone else, lockFile retries until the timeout
+// expires and returns ErrLocked. With zero timeout it fails immediately.
+func lockFile(f *os.File, readOnly bool, timeout time.Duration) error {
+	how := unix.LOCK_EX
+	if readOnly {
+		how = unix.LOCK_SH
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		err := unix.Flock(int(f.Fd()), how|unix.LOCK_NB)
+		if err == nil {
+			return nil
+		}
+
+		if err != unix.EWOULDBLOCK && err != unix.EINTR {
+			return errors.Wrapf(err, "while locking %s", f.Name())
+		}
+
+		if !time.Now().Before(deadline) {
+			return errors.Wrapf(ErrLocked, "while locking %s", f.Name())
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+}
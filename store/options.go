@@ -0,0 +1,57 @@
+package store
+
+import (
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type Options struct {
+	// MaxSize is the size of the memory mapped region, the store can't grow
+	// larger than MaxSize.
+	MaxSize int
+	// GrowthIncrement is the number of bytes the file is extended by when
+	// it runs out of space. Defaults to 16 MiB.
+	GrowthIncrement uint64
+	// FileMode is used when creating the store and journal files.
+	// Defaults to 0600.
+	FileMode os.FileMode
+	// ReadOnly opens the store file read-only and maps it with read
+	// permission only. All modifications return ErrReadOnly.
+	ReadOnly bool
+	// LockTimeout is how long Open waits for a store locked by another
+	// process before returning ErrLocked. Zero fails immediately.
+	LockTimeout time.Duration
+	// VerifyChecksums makes GetBlock verify the checksum of every block it
+	// returns and return ErrCorrupt on mismatch.
+	VerifyChecksums bool
+}
+
+const defaultGrowthIncrement = 16 * 1024 * 1024
+
+const defaultFileMode os.FileMode = 0600
+
+func (o Options) withDefaults() Options {
+	if o.GrowthIncrement == 0 {
+		o.GrowthIncrement = defaultGrowthIncrement
+	}
+
+	if o.FileMode == 0 {
+		o.FileMode = defaultFileMode
+	}
+
+	return o
+}
+
+func (o Options) validate() error {
+	if o.LockTimeout < 0 {
+		return errors.Errorf("lock timeout must not be negative, got %s", o.LockTimeout)
+	}
+
+	if o.MaxSize < headerSize {
+		return errors.Errorf("max size %d is smaller than the header size %d", o.MaxSize, headerSize)
+	}
+
+	return nil
+}
@@ -1,12 +1,34 @@
 package store
 
 import (
+	"sort"
+
 	"github.com/draganm/mmap-go"
 	"github.com/pkg/errors"
 )
 
+// PrivateMMap creates a copy-on-write view of the store.
+// Changes made to the returned store are not visible in this store until
+// they are committed.
+// The private store works on the state slot that is not current, so
+// committing it only has to switch the current state slot.
+// Blocks allocated past the committed next free address are written
+// directly to the store file, they are not reachable before commit.
+// Changes made directly to a store that is not a private memory map are
+// not crash safe.
 func (s *Store) PrivateMMap() (*Store, error) {
 
+	if s.readOnly {
+		return nil, ErrReadOnly
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failed {
+		return nil, ErrCommitFailed
+	}
+
 	// use https://godoc.org/github.com/riobard/go-mmap
 
 	// return Open(s.dir, s.maxSize, true)
@@ -20,12 +42,220 @@ func (s *Store) PrivateMMap() (*Store, error) {
 		return nil, errors.Wrap(err, "while memory mapping CoW")
 	}
 
+	stateOffset := otherStateSlot(s.stateOffset)
+	copy(mm[stateOffset:stateOffset+stateSize], s.state())
+
 	return &Store{
 		currentSize: s.currentSize,
 		dir:         s.dir,
 		f:           s.f,
 		maxSize:     s.maxSize,
 		mm:          mm,
+		parent:      s,
+		dirty:       map[Address]struct{}{},
+		stateOffset: stateOffset,
+		journal:     s.journal,
+		sharedFrom:  s.nextFreeAddress().UInt64(),
+
+		verifyChecksums: s.verifyChecksums,
+
+		growthIncrement: s.growthIncrement,
 	}, nil
 
 }
+
+type blockRange struct {
+	start uint64
+	end   uint64
+}
+
+func (s *Store) dirtyBlockRanges() []blockRange {
+	ranges := make([]blockRange, 0, len(s.dirty))
+
+	for a := range s.dirty {
+		start := blockStart(a)
+		ranges = append(ranges, blockRange{
+			start: start,
+			end:   start + uint64(1)<<s.blockMemory(start)[start],
+		})
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].start < ranges[j].start
+	})
+
+	return ranges
+}
+
+// Commit durably publishes all blocks allocated or touched in the private
+// memory map to the store it was created from.
+// First the blocks past the committed state, which are already written to
+// the store, are synced. Then the new content of blocks that were part of
+// the committed state is written to the journal and synced, copied to the
+// store and synced. Finally the new state is written to the state slot
+// that was not current and synced, which makes it the current state.
+// The private memory map is unmapped afterwards, also when the commit fails.
+// If the commit fails after the store has been changed, the store has to
+// be reopened, which recovers the commit from the journal.
+func (s *Store) Commit() error {
+	if s.parent == nil {
+		return errors.New("trying to commit a store that is not a private memory map")
+	}
+
+	err := s.commit()
+	if err != nil {
+		s.unmapPrivate()
+		return err
+	}
+
+	return s.unmapPrivate()
+}
+
+func (s *Store) commit() error {
+	p := s.parent
+
+	s.setTxID(p.TxID() + 1)
+
+	ranges := s.dirtyBlockRanges()
+
+	for _, r := range ranges {
+		s.seal(r)
+	}
+
+	// the journaled state references the new blocks, they have to be on the
+	// disk before the journal
+	newFrom := sort.Search(len(ranges), func(i int) bool {
+		return ranges[i].start >= s.sharedFrom
+	})
+
+	err := p.syncRanges(ranges[newFrom:])
+	if err != nil {
+		return errors.Wrap(err, "while syncing new blocks")
+	}
+
+	journaled, err := s.journalCommittedBlocks(ranges)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if s.currentSize > p.currentSize {
+		p.currentSize = s.currentSize
+	}
+
+	err = p.preserveSnapshots(ranges[:newFrom])
+	if err != nil {
+		return err
+	}
+
+	for _, r := range ranges[:newFrom] {
+		copy(p.mm[r.start:r.end], s.mm[r.start:r.end])
+	}
+
+	err = p.syncRanges(ranges[:newFrom])
+	if err != nil {
+		p.failed = true
+		return errors.Wrap(err, "while syncing blocks")
+	}
+
+	copy(p.mm[s.stateOffset:s.stateOffset+stateSize], s.state())
+
+	err = p.sync(0, headerSize)
+	if err != nil {
+		p.failed = true
+		return errors.Wrap(err, "while syncing state")
+	}
+
+	p.stateOffset = s.stateOffset
+
+	if journaled {
+		err = p.journal.Truncate(0)
+		if err != nil {
+			return errors.Wrap(err, "while truncating journal")
+		}
+	}
+
+	return nil
+}
+
+// syncRanges flushes the block ranges, sorted by their start, to the disk.
+func (s *Store) syncRanges(ranges []blockRange) error {
+	for len(ranges) > 0 {
+		// sync adjacent blocks at once
+		r := ranges[0]
+		ranges = ranges[1:]
+		for len(ranges) > 0 && ranges[0].start <= r.end+systemPageSize {
+			if ranges[0].end > r.end {
+				r.end = ranges[0].end
+			}
+			ranges = ranges[1:]
+		}
+
+		err := s.sync(r.start, r.end)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// journalCommittedBlocks writes the changed blocks that were part of the
+// committed state and the new state to the journal.
+// Blocks past the committed next free address are not reachable from the
+// committed state and don't have to be journaled.
+func (s *Store) journalCommittedBlocks(ranges []blockRange) (bool, error) {
+	p := s.parent
+
+	committedEnd := p.nextFreeAddress().UInt64()
+
+	entries := []journalEntry{}
+
+	for _, r := range ranges {
+		if r.start < committedEnd {
+			entries = append(entries, journalEntry{
+				offset: r.start,
+				data:   s.mm[r.start:r.end],
+			})
+		}
+	}
+
+	if len(entries) == 0 {
+		return false, nil
+	}
+
+	err := writeJournal(p.journal, s.TxID(), s.stateOffset, s.state(), entries)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Rollback discards all changes made in the private memory map.
+func (s *Store) Rollback() error {
+	if s.parent == nil {
+		return errors.New("trying to roll back a store that is not a private memory map")
+	}
+
+	p := s.parent
+
+	p.mu.Lock()
+	if s.currentSize > p.currentSize {
+		p.currentSize = s.currentSize
+	}
+	p.mu.Unlock()
+
+	return s.unmapPrivate()
+}
+
+func (s *Store) unmapPrivate() error {
+	err := s.mm.Unmap()
+	if err != nil {
+		return errors.Wrap(err, "while unmapping private memory map")
+	}
+	s.dirty = nil
+	return nil
+}
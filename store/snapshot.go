@@ -0,0 +1,165 @@
+package store
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/draganm/mmap-go"
+	"github.com/pkg/errors"
+)
+
+// Snapshot returns a read-only view of the last committed state of the
+// store.
+// The snapshot has its own copy-on-write memory map of the store file.
+// Before a commit overwrites blocks of the committed state, their pages
+// are copied into the memory maps of all open snapshots, so commits don't
+// wait for snapshots and don't change what they read.
+// Changes made directly to a store that is not a private memory map are
+// not kept away from its snapshots.
+// Snapshots of the same committed state share their memory map, every
+// snapshot has to be closed with Close.
+func (s *Store) Snapshot() (*Store, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failed {
+		return nil, ErrCommitFailed
+	}
+
+	if s.latest != nil && s.latest.TxID() == s.TxID() {
+		s.latest.refs++
+		return s.latest, nil
+	}
+
+	// a snapshot never reads past its next free address, so mapping the
+	// whole max size would only waste address space
+	size := s.nextFreeAddress().UInt64()
+	size += (systemPageSize - size%systemPageSize) % systemPageSize
+	if size > s.currentSize {
+		size = s.currentSize
+	}
+
+	mm, err := mmap.MapRegion(s.f, int(size), mmap.RDWR|mmap.COPY|mmap.NORESERVE, 0, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "while memory mapping snapshot")
+	}
+
+	// pin the state of the snapshot
+	preservePages(mm, 0, headerSize)
+
+	snap := &Store{
+		dir:         s.dir,
+		f:           s.f,
+		mm:          mm,
+		currentSize: size,
+		maxSize:     s.maxSize,
+		stateOffset: s.stateOffset,
+		readOnly:    true,
+		snapshotOf:  s,
+		refs:        1,
+
+		verifyChecksums: s.verifyChecksums,
+
+		growthIncrement: s.growthIncrement,
+	}
+
+	if s.snapshots == nil {
+		s.snapshots = map[*Store]struct{}{}
+	}
+
+	s.snapshots[snap] = struct{}{}
+	s.latest = snap
+
+	return snap, nil
+}
+
+// preservePages makes the memory map keep its own copy of the pages
+// containing the bytes from-to, so that later changes of the store file
+// are not visible in them.
+// Adding zero doesn't change the content, but the write forces the kernel
+// to copy the page into the copy-on-write memory map.
+func preservePages(mm mmap.MMap, from, to uint64) {
+	for o := from - from%systemPageSize; o < to; o += systemPageSize {
+		atomic.AddUint32((*uint32)(unsafe.Pointer(&mm[o])), 0)
+	}
+}
+
+// preserveSnapshots copies the pages of the ranges that are about to be
+// overwritten by a commit into all open snapshots and discards unused
+// snapshots, which are not current anymore after the commit.
+// It has to be called with s.mu held.
+func (s *Store) preserveSnapshots(ranges []blockRange) error {
+	s.latest = nil
+
+	for snap := range s.snapshots {
+		if snap.refs == 0 {
+			delete(s.snapshots, snap)
+			err := snap.mm.Unmap()
+			if err != nil {
+				return errors.Wrap(err, "while unmapping snapshot")
+			}
+			continue
+		}
+
+		nfa := snap.nextFreeAddress().UInt64()
+
+		for _, r := range ranges {
+			if r.start >= nfa {
+				continue
+			}
+
+			end := r.end
+			if end > uint64(len(snap.mm)) {
+				end = uint64(len(snap.mm))
+			}
+
+			preservePages(snap.mm, r.start, end)
+		}
+	}
+
+	return nil
+}
+
+// releaseSnapshot is called when the snapshot is closed, its memory map
+// is kept for the next snapshot until the state changes.
+func (s *Store) releaseSnapshot(snap *Store) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if snap.refs == 0 {
+		return errors.New("snapshot is already closed")
+	}
+
+	snap.refs--
+
+	if snap.refs > 0 || snap == s.latest {
+		return nil
+	}
+
+	delete(s.snapshots, snap)
+
+	err := snap.mm.Unmap()
+	if err != nil {
+		return errors.Wrap(err, "while unmapping snapshot")
+	}
+
+	return nil
+}
+
+// closeSnapshots unmaps the memory maps of all snapshots of the store.
+func (s *Store) closeSnapshots() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for snap := range s.snapshots {
+		err := snap.mm.Unmap()
+		if err != nil {
+			return errors.Wrap(err, "while unmapping snapshot")
+		}
+	}
+
+	s.snapshots = nil
+	s.latest = nil
+
+	return nil
+}
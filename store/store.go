@@ -2,8 +2,10 @@ package store
 
 import (
 	"encoding/binary"
+	serrors "errors"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/draganm/mmap-go"
 	"github.com/pkg/errors"
@@ -16,57 +18,200 @@ type Store struct {
 	mm          mmap.MMap
 	currentSize uint64
 	maxSize     int
+	parent      *Store
+	dirty       map[Address]struct{}
+	stateOffset uint64
+	journal     *os.File
+	readOnly    bool
+
+	// sharedFrom is the offset from which blocks of a private memory map
+	// are accessed through the shared memory map of its parent.
+	sharedFrom uint64
+
+	// mu guards the snapshots and the publication of committed state.
+	mu        sync.Mutex
+	snapshots map[*Store]struct{}
+	// latest is the snapshot of the current state, it is kept open to be
+	// shared by following snapshots.
+	latest *Store
+	// snapshotOf is the store the snapshot was created from, refs is the
+	// number of users of the snapshot.
+	snapshotOf *Store
+	refs       int
+	// failed is set when a commit failed after changing the store.
+	failed bool
+
+	verifyChecksums bool
+
+	growthIncrement uint64
 }
 
+var ErrReadOnly = serrors.New("store is opened read-only")
+
+// ErrFull is returned when an allocation would grow the store past its
+// maximum size.
+var ErrFull = serrors.New("store reached its maximum size")
+
+var ErrCommitFailed = serrors.New("commit failed after changing the store, it has to be reopened")
+
 func Open(dir string, maxSize int) (*Store, error) {
+	return OpenWithOptions(dir, Options{MaxSize: maxSize})
+}
+
+func OpenWithOptions(dir string, o Options) (*Store, error) {
+	o = o.withDefaults()
+
+	err := o.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	maxSize := o.MaxSize
+
+	flags := os.O_CREATE | os.O_RDWR | os.O_APPEND
+	if o.ReadOnly {
+		flags = os.O_RDONLY
+	}
+
 	storeFileName := filepath.Join(dir, "db")
-	f, err := os.OpenFile(storeFileName, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	f, err := os.OpenFile(storeFileName, flags, o.FileMode)
 	if err != nil {
 		return nil, errors.Wrapf(err, "while opening file %s", storeFileName)
 	}
 
+	err = lockFile(f, o.ReadOnly, o.LockTimeout)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
 	st, err := f.Stat()
 	if err != nil {
+		f.Close()
 		return nil, errors.Wrapf(err, "while getting stats of file %s", storeFileName)
 	}
 
 	currentSize := uint64(st.Size())
 
-	if currentSize == 0 {
-		header := make([]byte, 16)
-		binary.LittleEndian.PutUint64(header, 16)
-		_, err = f.Write(header)
+	if currentSize == 0 && !o.ReadOnly {
+		_, err = f.Write(newHeader())
 		if err != nil {
+			f.Close()
 			return nil, errors.Wrapf(err, "while appending header to %s", storeFileName)
 		}
-		currentSize = 16
+
+		err = f.Sync()
+		if err != nil {
+			f.Close()
+			return nil, errors.Wrapf(err, "while syncing header of %s", storeFileName)
+		}
+
+		currentSize = headerSize
+	}
+
+	if currentSize < headerSize {
+		f.Close()
+		return nil, errors.Wrapf(ErrNotStoreFile, "file %s is smaller than the header", storeFileName)
+	}
+
+	if currentSize > uint64(maxSize) {
+		f.Close()
+		return nil, errors.Errorf("file %s of %d bytes is larger than the max size %d", storeFileName, currentSize, maxSize)
 	}
 
 	mmFlags := mmap.RDWR
+	if o.ReadOnly {
+		mmFlags = mmap.RDONLY
+	}
 
 	mm, err := mmap.MapRegion(f, maxSize, mmFlags, 0, 0)
 
 	if err != nil {
+		f.Close()
 		return nil, errors.Wrapf(err, "while memory mapping file %s", storeFileName)
 	}
 
+	err = validateHeader(mm)
+	if err != nil {
+		mm.Unmap()
+		f.Close()
+		return nil, errors.Wrapf(err, "while validating header of %s", storeFileName)
+	}
+
 	err = unix.Madvise(mm, unix.MADV_RANDOM)
 	if err != nil {
+		mm.Unmap()
+		f.Close()
 		return nil, errors.Wrapf(err, "while setting madvise to random for segment file %q", storeFileName)
 	}
 
-	return &Store{
+	stateOffset, err := currentStateSlot(mm)
+	if err != nil {
+		mm.Unmap()
+		f.Close()
+		return nil, errors.Wrapf(err, "while reading state of %s", storeFileName)
+	}
+
+	nfa := binary.LittleEndian.Uint64(mm[stateOffset+stateNextFreeAddressOffset:])
+	if nfa < headerSize || nfa > currentSize {
+		mm.Unmap()
+		f.Close()
+		return nil, errors.Wrapf(ErrCorrupt, "next free address %d of %s is outside of the file of size %d", nfa, storeFileName, currentSize)
+	}
+
+	s := &Store{
 		dir:         dir,
 		f:           f,
 		mm:          mm,
 		currentSize: currentSize,
 		maxSize:     maxSize,
-	}, nil
+		stateOffset: stateOffset,
+		readOnly:    o.ReadOnly,
+
+		verifyChecksums: o.VerifyChecksums,
+
+		growthIncrement: o.GrowthIncrement,
+	}
+
+	journalFileName := filepath.Join(dir, journalFileName)
+
+	if o.ReadOnly {
+		err = s.checkJournal(journalFileName)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+
+		return s, nil
+	}
+
+	s.journal, err = os.OpenFile(journalFileName, os.O_CREATE|os.O_RDWR, o.FileMode)
+	if err != nil {
+		s.Close()
+		return nil, errors.Wrapf(err, "while opening journal %s", journalFileName)
+	}
+
+	err = s.recoverJournal()
+	if err != nil {
+		s.Close()
+		return nil, errors.Wrapf(err, "while recovering journal %s", journalFileName)
+	}
+
+	return s, nil
 
 }
 
 func (s *Store) Close() error {
-	err := s.mm.Unmap()
+	if s.snapshotOf != nil {
+		return s.snapshotOf.releaseSnapshot(s)
+	}
+
+	err := s.closeSnapshots()
+	if err != nil {
+		return err
+	}
+
+	err = s.mm.Unmap()
 	if err != nil {
 		return errors.Wrapf(err, "while unmmaping %q", s.f.Name())
 	}
@@ -76,13 +221,61 @@ func (s *Store) Close() error {
 		return errors.Wrapf(err, "while closing %s", s.f.Name())
 	}
 
+	if s.journal != nil {
+		err = s.journal.Close()
+		if err != nil {
+			return errors.Wrapf(err, "while closing %s", s.journal.Name())
+		}
+	}
+
+	return nil
+}
+
+// Trim truncates the store file to the end of the last allocated block.
+// It must not be called while a private memory map of the store is in use.
+func (s *Store) Trim() error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	end := s.nextFreeAddress().UInt64()
+
+	err := s.f.Truncate(int64(end))
+	if err != nil {
+		return errors.Wrapf(err, "while truncating %s", s.f.Name())
+	}
+
+	err = s.f.Sync()
+	if err != nil {
+		return errors.Wrapf(err, "while syncing %s", s.f.Name())
+	}
+
+	s.currentSize = end
+
+	return nil
+}
+
+// systemPageSize is the size of memory pages of the operating system,
+// it can differ from the page size of the store file.
+var systemPageSize = uint64(os.Getpagesize())
+
+// sync flushes the memory mapped pages containing the bytes from-to
+// to the disk.
+func (s *Store) sync(from, to uint64) error {
+	from -= from % systemPageSize
+	err := unix.Msync(s.mm[from:to], unix.MS_SYNC)
+	if err != nil {
+		return errors.Wrap(err, "while syncing memory map")
+	}
 	return nil
 }
 
-const sizeIncrease = 16 * 1024 * 1024
+// minBlockBits makes every block large enough to hold the address of
+// the next free block once it has been freed.
+const minBlockBits = 4
 
 func bitsForSize(size int) int {
-	var bits = 3
+	var bits = minBlockBits
 
 	for ; size>>bits > 0; bits++ {
 	}
@@ -93,20 +286,58 @@ func bitsForSize(size int) int {
 
 func (s *Store) Allocate(size int, t BlockType) (Address, []byte, error) {
 
-	bits := bitsForSize(size + 2)
+	if s.readOnly {
+		return NilAddress, nil, ErrReadOnly
+	}
+
+	bits := bitsForSize(size + blockHeaderSize)
 	bitsSize := 1 << bits
 
+	fa := s.freeListHead(bits)
+	if fa != NilAddress {
+		fbits, ft, err := s.checkBlock(fa)
+		if err != nil {
+			return NilAddress, nil, errors.Wrap(err, "while getting block from the free list")
+		}
+
+		if fbits != bits || ft != FreeBlockType {
+			return NilAddress, nil, corruptBlock(fa, "block on the free list of size class %d is not a free block of that size", bits)
+		}
+
+		mm := s.blockMemory(blockStart(fa))
+		bl := mm[fa : blockStart(fa)+uint64(bitsSize)]
+		s.setFreeListHead(bits, s.nextFreeBlock(fa))
+
+		for i := range bl {
+			bl[i] = 0
+		}
+
+		mm[blockStart(fa)+blockTypeOffset] = byte(t)
+		s.markDirty(fa)
+
+		return fa, bl[:size], nil
+	}
+
 	nfa := s.nextFreeAddress().UInt64()
 	end := nfa + uint64(bitsSize)
+
+	if end > uint64(s.maxSize) {
+		return NilAddress, nil, errors.Wrapf(ErrFull, "block of %d bytes does not fit into the max size %d", bitsSize, s.maxSize)
+	}
+
 	if end > s.currentSize {
 		missing := end - s.currentSize
-		toAppend := missing / sizeIncrease
+		toAppend := missing / s.growthIncrement
 
-		if (missing % sizeIncrease) != 0 {
+		if (missing % s.growthIncrement) != 0 {
 			toAppend++
 		}
 
-		toAppend *= sizeIncrease
+		toAppend *= s.growthIncrement
+
+		if s.currentSize+toAppend > uint64(s.maxSize) {
+			toAppend = uint64(s.maxSize) - s.currentSize
+		}
 
 		err := s.f.Truncate(int64(s.currentSize + toAppend))
 		if err != nil {
@@ -122,64 +353,117 @@ func (s *Store) Allocate(size int, t BlockType) (Address, []byte, error) {
 	}
 
 	// DON'T REMOVE: write new NFA
-	binary.LittleEndian.PutUint64(s.mm[:8], end)
+	s.setNextFreeAddress(Address(end))
 
-	s.mm[nfa] = byte(bits)
-	s.mm[nfa+1] = byte(t)
+	addr := Address(nfa + blockHeaderSize)
 
-	return Address(nfa + 2), s.mm[nfa+2 : nfa+2+uint64(size)], nil
+	mm := s.blockMemory(nfa)
 
-}
+	mm[nfa] = byte(bits)
+	mm[nfa+blockTypeOffset] = byte(t)
+
+	// the space past the next free address can contain leftovers of
+	// transactions that were rolled back
+	bl := mm[addr:end]
+	for i := range bl {
+		bl[i] = 0
+	}
+
+	s.markDirty(addr)
+
+	return addr, bl[:size], nil
 
-func (s *Store) nextFreeAddress() Address {
-	return Address(binary.LittleEndian.Uint64(s.mm[:8]))
 }
 
 func (s *Store) GetBlock(addr Address) ([]byte, BlockType, error) {
 
 	if addr == NilAddress {
-		return nil, 0, errors.New("trying to get block with NIL address")
+		return nil, 0, corruptBlock(addr, "nil address")
+	}
+
+	bits, t, err := s.checkBlock(addr)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if s.verifyChecksums {
+		err = s.verifyChecksum(addr, bits)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return s.blockMemory(blockStart(addr))[addr : blockStart(addr)+uint64(1)<<bits], t, nil
+}
+
+// Free puts the block at the address on the free list of its size class,
+// so that it can be reused by Allocate.
+func (s *Store) Free(addr Address) error {
+	if s.readOnly {
+		return ErrReadOnly
 	}
 
-	nfa := s.nextFreeAddress()
-	if addr >= nfa {
-		return nil, 0, errors.New("block is past the highest address")
+	bits, t, err := s.checkBlock(addr)
+	if err != nil {
+		return errors.Wrap(err, "while getting block to free")
 	}
 
-	bld := s.mm[addr-2:]
-	bits := bld[0]
+	if t == FreeBlockType {
+		return errors.Errorf("block %d is already free", addr)
+	}
 
-	l := 1 << int(bits)
+	mm := s.blockMemory(blockStart(addr))
+	mm[blockStart(addr)+blockTypeOffset] = byte(FreeBlockType)
+	binary.LittleEndian.PutUint64(mm[addr:], s.freeListHead(bits).UInt64())
+	s.setFreeListHead(bits, addr)
+	s.markDirty(addr)
 
-	bld = bld[:l]
-	if len(bld) < 2 {
-		return nil, 0, errors.New("block is too short")
+	if s.dirty == nil {
+		start := blockStart(addr)
+		s.seal(blockRange{start: start, end: start + uint64(1)<<bits})
 	}
-	t := BlockType(bld[1])
 
-	return bld[2:], t, nil
+	return nil
 }
 
-func (s *Store) Free(Address) error {
-	return errors.New("not yet implemented")
+// nextFreeBlock returns the address of the block following the free block
+// on its free list.
+func (s *Store) nextFreeBlock(addr Address) Address {
+	return Address(binary.LittleEndian.Uint64(s.blockMemory(blockStart(addr))[addr:]))
 }
 
+// Touch marks the block as changed.
+// Changed blocks of a private memory map are sealed and published on
+// commit, blocks of a store modified directly are sealed immediately.
 func (s *Store) Touch(addr Address) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	bits, _, err := s.checkBlock(addr)
+	if err != nil {
+		return err
+	}
+
+	if s.dirty == nil {
+		start := blockStart(addr)
+		s.seal(blockRange{start: start, end: start + uint64(1)<<bits})
+		return nil
+	}
+
+	s.markDirty(addr)
 	return nil
 }
 
+func (s *Store) markDirty(addr Address) {
+	if s.dirty != nil {
+		s.dirty[addr] = struct{}{}
+	}
+}
+
 type Memory interface {
 	Allocate(size int, t BlockType) (Address, []byte, error)
 	Free(Address) error
 	GetBlock(addr Address) ([]byte, BlockType, error)
 	Touch(Address) error
 }
-
-func (s *Store) GetRootAddress() Address {
-	return Address(binary.LittleEndian.Uint64(s.mm[8:]))
-}
-
-func (s *Store) SetRootAddress(a Address) error {
-	binary.LittleEndian.PutUint64(s.mm[8:], a.UInt64())
-	return nil
-}
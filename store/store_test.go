@@ -1,9 +1,15 @@
 package store_test
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/draganm/l5db/store"
 	"github.com/stretchr/testify/require"
@@ -21,7 +27,7 @@ func TestOpenEmptyStore(t *testing.T) {
 	td, cleanup := tempDir(t)
 	defer cleanup()
 
-	st, err := store.Open(td, 1024)
+	st, err := store.Open(td, 1024*1024)
 	require.NoError(t, err)
 
 	err = st.Close()
@@ -32,12 +38,12 @@ func TestAllocate(t *testing.T) {
 	td, cleanup := tempDir(t)
 	defer cleanup()
 
-	st, err := store.Open(td, 1024)
+	st, err := store.Open(td, 1024*1024)
 	require.NoError(t, err)
 
 	addr, d, err := st.Allocate(3, store.BTreeMetaBlockType)
 	require.NoError(t, err)
-	require.Equal(t, store.Address(18), addr)
+	require.Equal(t, store.Address(4102), addr)
 
 	copy(d, []byte{1, 2, 3})
 
@@ -47,7 +53,7 @@ func TestAllocate(t *testing.T) {
 	err = st.Close()
 	require.NoError(t, err)
 
-	st, err = store.Open(td, 1024)
+	st, err = store.Open(td, 1024*1024)
 	require.NoError(t, err)
 	defer st.Close()
 
@@ -58,3 +64,430 @@ func TestAllocate(t *testing.T) {
 	require.Equal(t, []byte{1, 2, 3}, bl[:3])
 
 }
+
+func TestFreeAndReuse(t *testing.T) {
+	td, cleanup := tempDir(t)
+	defer cleanup()
+
+	st, err := store.Open(td, 1024*1024)
+	require.NoError(t, err)
+
+	addr, d, err := st.Allocate(100, store.SequentialDataBlockType)
+	require.NoError(t, err)
+	copy(d, []byte{1, 2, 3})
+
+	other, _, err := st.Allocate(20, store.SequentialDataBlockType)
+	require.NoError(t, err)
+
+	err = st.Free(addr)
+	require.NoError(t, err)
+
+	err = st.Free(addr)
+	require.Error(t, err)
+
+	_, bt, err := st.GetBlock(addr)
+	require.NoError(t, err)
+	require.Equal(t, store.FreeBlockType, bt)
+
+	err = st.Free(other)
+	require.NoError(t, err)
+
+	err = st.Close()
+	require.NoError(t, err)
+
+	st, err = store.Open(td, 1024*1024)
+	require.NoError(t, err)
+	defer st.Close()
+
+	t.Run("allocating block of the same size class reuses freed block", func(t *testing.T) {
+		ra, rd, err := st.Allocate(110, store.BTreeLeafBlockType)
+		require.NoError(t, err)
+		require.Equal(t, addr, ra)
+		require.Len(t, rd, 110)
+		require.Equal(t, make([]byte, 110), rd)
+
+		_, bt, err := st.GetBlock(ra)
+		require.NoError(t, err)
+		require.Equal(t, store.BTreeLeafBlockType, bt)
+	})
+
+	t.Run("allocating block of a different size class does not reuse freed block", func(t *testing.T) {
+		ra, _, err := st.Allocate(1000, store.BTreeLeafBlockType)
+		require.NoError(t, err)
+		require.NotEqual(t, addr, ra)
+		require.NotEqual(t, other, ra)
+	})
+
+}
+
+func TestOpenValidatesHeader(t *testing.T) {
+
+	t.Run("foreign file", func(t *testing.T) {
+		td, cleanup := tempDir(t)
+		defer cleanup()
+
+		err := ioutil.WriteFile(filepath.Join(td, "db"), bytes.Repeat([]byte("not a db"), 1024), 0600)
+		require.NoError(t, err)
+
+		_, err = store.Open(td, 1024*1024)
+		require.True(t, errors.Is(err, store.ErrNotStoreFile))
+	})
+
+	t.Run("truncated file", func(t *testing.T) {
+		td, cleanup := tempDir(t)
+		defer cleanup()
+
+		err := ioutil.WriteFile(filepath.Join(td, "db"), []byte("l5db"), 0600)
+		require.NoError(t, err)
+
+		_, err = store.Open(td, 1024*1024)
+		require.True(t, errors.Is(err, store.ErrNotStoreFile))
+	})
+
+	t.Run("future major version", func(t *testing.T) {
+		td, cleanup := tempDir(t)
+		defer cleanup()
+
+		st, err := store.Open(td, 1024*1024)
+		require.NoError(t, err)
+
+		major, minor := st.FormatVersion()
+		require.Equal(t, uint16(store.FormatMajorVersion), major)
+		require.Equal(t, uint16(store.FormatMinorVersion), minor)
+
+		err = st.Close()
+		require.NoError(t, err)
+
+		fileName := filepath.Join(td, "db")
+		d, err := ioutil.ReadFile(fileName)
+		require.NoError(t, err)
+
+		// bump major version and fix the superblock checksum
+		binary.LittleEndian.PutUint16(d[8:], store.FormatMajorVersion+1)
+		binary.LittleEndian.PutUint32(d[16:], crc32.ChecksumIEEE(d[:16]))
+
+		err = ioutil.WriteFile(fileName, d, 0600)
+		require.NoError(t, err)
+
+		_, err = store.Open(td, 1024*1024)
+		require.True(t, errors.Is(err, store.ErrIncompatibleVersion))
+	})
+
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	td, cleanup := tempDir(t)
+	defer cleanup()
+
+	err := ioutil.WriteFile(filepath.Join(td, "db"), nil, 0600)
+	require.NoError(t, err)
+
+	_, err = store.OpenWithOptions(td, store.Options{MaxSize: 1024 * 1024, ReadOnly: true})
+	require.True(t, errors.Is(err, store.ErrNotStoreFile))
+
+	fi, err := os.Stat(filepath.Join(td, "db"))
+	require.NoError(t, err)
+	require.Equal(t, int64(0), fi.Size())
+
+	err = os.Remove(filepath.Join(td, "db"))
+	require.NoError(t, err)
+
+	st, err := store.Open(td, 1024*1024)
+	require.NoError(t, err)
+	err = st.Close()
+	require.NoError(t, err)
+
+	st, err = store.OpenWithOptions(td, store.Options{MaxSize: 1024 * 1024, ReadOnly: true})
+	require.NoError(t, err)
+	defer st.Close()
+
+	_, _, err = st.Allocate(10, store.BTreeLeafBlockType)
+	require.True(t, errors.Is(err, store.ErrReadOnly))
+
+	_, err = st.PrivateMMap()
+	require.True(t, errors.Is(err, store.ErrReadOnly))
+}
+
+func TestOpenLocksStore(t *testing.T) {
+	td, cleanup := tempDir(t)
+	defer cleanup()
+
+	st, err := store.Open(td, 1024*1024)
+	require.NoError(t, err)
+
+	_, err = store.Open(td, 1024*1024)
+	require.True(t, errors.Is(err, store.ErrLocked))
+
+	_, err = store.OpenWithOptions(td, store.Options{MaxSize: 1024 * 1024, ReadOnly: true})
+	require.True(t, errors.Is(err, store.ErrLocked))
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		st.Close()
+	}()
+
+	st, err = store.OpenWithOptions(td, store.Options{MaxSize: 1024 * 1024, LockTimeout: 5 * time.Second})
+	require.NoError(t, err)
+	err = st.Close()
+	require.NoError(t, err)
+
+	r1, err := store.OpenWithOptions(td, store.Options{MaxSize: 1024 * 1024, ReadOnly: true})
+	require.NoError(t, err)
+	defer r1.Close()
+
+	r2, err := store.OpenWithOptions(td, store.Options{MaxSize: 1024 * 1024, ReadOnly: true})
+	require.NoError(t, err)
+	defer r2.Close()
+
+	_, err = store.OpenWithOptions(td, store.Options{MaxSize: 1024 * 1024, LockTimeout: 20 * time.Millisecond})
+	require.True(t, errors.Is(err, store.ErrLocked))
+}
+
+func TestGetBlockDetectsCorruption(t *testing.T) {
+	td, cleanup := tempDir(t)
+	defer cleanup()
+
+	st, err := store.Open(td, 1024*1024)
+	require.NoError(t, err)
+
+	addr, d, err := st.Allocate(10, store.BTreeLeafBlockType)
+	require.NoError(t, err)
+
+	copy(d, []byte("0123456789"))
+
+	err = st.Touch(addr)
+	require.NoError(t, err)
+
+	err = st.Close()
+	require.NoError(t, err)
+
+	fileName := filepath.Join(td, "db")
+
+	// corrupt overwrites the byte at the offset and returns a function
+	// restoring it
+	corrupt := func(offset uint64, value byte) func() {
+		f, err := os.OpenFile(fileName, os.O_RDWR, 0600)
+		require.NoError(t, err)
+		defer f.Close()
+
+		original := []byte{0}
+		_, err = f.ReadAt(original, int64(offset))
+		require.NoError(t, err)
+
+		_, err = f.WriteAt([]byte{value}, int64(offset))
+		require.NoError(t, err)
+
+		return func() {
+			f, err := os.OpenFile(fileName, os.O_RDWR, 0600)
+			require.NoError(t, err)
+			defer f.Close()
+			_, err = f.WriteAt(original, int64(offset))
+			require.NoError(t, err)
+		}
+	}
+
+	requireCorrupt := func(err error) {
+		require.True(t, errors.Is(err, store.ErrCorrupt))
+		var cbe *store.CorruptBlockError
+		require.True(t, errors.As(err, &cbe))
+		require.Equal(t, addr, cbe.Address)
+	}
+
+	t.Run("invalid size class", func(t *testing.T) {
+		defer corrupt(addr.UInt64()-6, 200)()
+
+		st, err := store.Open(td, 1024*1024)
+		require.NoError(t, err)
+		defer st.Close()
+
+		_, _, err = st.GetBlock(addr)
+		requireCorrupt(err)
+	})
+
+	t.Run("block past the end", func(t *testing.T) {
+		defer corrupt(addr.UInt64()-6, 40)()
+
+		st, err := store.Open(td, 1024*1024)
+		require.NoError(t, err)
+		defer st.Close()
+
+		_, _, err = st.GetBlock(addr)
+		requireCorrupt(err)
+	})
+
+	t.Run("unknown block type", func(t *testing.T) {
+		defer corrupt(addr.UInt64()-5, 99)()
+
+		st, err := store.Open(td, 1024*1024)
+		require.NoError(t, err)
+		defer st.Close()
+
+		_, _, err = st.GetBlock(addr)
+		requireCorrupt(err)
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		defer corrupt(addr.UInt64()+3, 'x')()
+
+		st, err := store.Open(td, 1024*1024)
+		require.NoError(t, err)
+
+		_, _, err = st.GetBlock(addr)
+		require.NoError(t, err)
+
+		err = st.Close()
+		require.NoError(t, err)
+
+		st, err = store.OpenWithOptions(td, store.Options{MaxSize: 1024 * 1024, VerifyChecksums: true})
+		require.NoError(t, err)
+		defer st.Close()
+
+		_, _, err = st.GetBlock(addr)
+		requireCorrupt(err)
+	})
+
+	t.Run("nil address", func(t *testing.T) {
+		st, err := store.Open(td, 1024*1024)
+		require.NoError(t, err)
+		defer st.Close()
+
+		_, _, err = st.GetBlock(store.NilAddress)
+		require.True(t, errors.Is(err, store.ErrCorrupt))
+	})
+
+	t.Run("truncated file", func(t *testing.T) {
+		err := os.Truncate(fileName, 4096)
+		require.NoError(t, err)
+
+		_, err = store.Open(td, 1024*1024)
+		require.True(t, errors.Is(err, store.ErrCorrupt))
+	})
+}
+
+func TestAllocateAfterRollbackReturnsZeroedBlock(t *testing.T) {
+	td, cleanup := tempDir(t)
+	defer cleanup()
+
+	st, err := store.Open(td, 1024*1024)
+	require.NoError(t, err)
+	defer st.Close()
+
+	pm, err := st.PrivateMMap()
+	require.NoError(t, err)
+
+	addr, d, err := pm.Allocate(100, store.SequentialDataBlockType)
+	require.NoError(t, err)
+
+	copy(d, bytes.Repeat([]byte{0xff}, 100))
+
+	err = pm.Rollback()
+	require.NoError(t, err)
+
+	pm, err = st.PrivateMMap()
+	require.NoError(t, err)
+	defer pm.Rollback()
+
+	addr2, d, err := pm.Allocate(100, store.SequentialDataBlockType)
+	require.NoError(t, err)
+	require.Equal(t, addr, addr2)
+	require.Equal(t, make([]byte, 100), d)
+}
+
+func TestSnapshot(t *testing.T) {
+	td, cleanup := tempDir(t)
+	defer cleanup()
+
+	st, err := store.Open(td, 1024*1024)
+	require.NoError(t, err)
+	defer st.Close()
+
+	pm, err := st.PrivateMMap()
+	require.NoError(t, err)
+
+	addr, d, err := pm.Allocate(3, store.BTreeLeafBlockType)
+	require.NoError(t, err)
+	copy(d, []byte{1, 2, 3})
+
+	err = pm.Touch(addr)
+	require.NoError(t, err)
+
+	err = pm.SetRootAddress(addr)
+	require.NoError(t, err)
+
+	err = pm.Commit()
+	require.NoError(t, err)
+
+	snap, err := st.Snapshot()
+	require.NoError(t, err)
+
+	_, _, err = snap.Allocate(10, store.BTreeLeafBlockType)
+	require.True(t, errors.Is(err, store.ErrReadOnly))
+
+	pm, err = st.PrivateMMap()
+	require.NoError(t, err)
+
+	d, _, err = pm.GetBlock(addr)
+	require.NoError(t, err)
+	copy(d, []byte{4, 5, 6})
+
+	err = pm.Touch(addr)
+	require.NoError(t, err)
+
+	other, _, err := pm.Allocate(3, store.BTreeLeafBlockType)
+	require.NoError(t, err)
+
+	err = pm.SetRootAddress(other)
+	require.NoError(t, err)
+
+	err = pm.Commit()
+	require.NoError(t, err)
+
+	require.Equal(t, addr, snap.GetRootAddress())
+
+	d, _, err = snap.GetBlock(addr)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, d[:3])
+
+	_, _, err = snap.GetBlock(other)
+	require.True(t, errors.Is(err, store.ErrCorrupt))
+
+	current, err := st.Snapshot()
+	require.NoError(t, err)
+	defer current.Close()
+
+	require.Equal(t, other, current.GetRootAddress())
+
+	d, _, err = current.GetBlock(addr)
+	require.NoError(t, err)
+	require.Equal(t, []byte{4, 5, 6}, d[:3])
+
+	err = snap.Close()
+	require.NoError(t, err)
+
+	err = snap.Close()
+	require.Error(t, err)
+}
+
+func TestMaxSize(t *testing.T) {
+	td, cleanup := tempDir(t)
+	defer cleanup()
+
+	st, err := store.OpenWithOptions(td, store.Options{MaxSize: 1024 * 1024, GrowthIncrement: 1024 * 1024})
+	require.NoError(t, err)
+
+	_, _, err = st.Allocate(1024*1024, store.SequentialDataBlockType)
+	require.True(t, errors.Is(err, store.ErrFull))
+
+	_, _, err = st.Allocate(256*1024, store.SequentialDataBlockType)
+	require.NoError(t, err)
+
+	fi, err := os.Stat(filepath.Join(td, "db"))
+	require.NoError(t, err)
+	require.Equal(t, int64(1024*1024), fi.Size())
+
+	err = st.Close()
+	require.NoError(t, err)
+
+	_, err = store.OpenWithOptions(td, store.Options{MaxSize: 512 * 1024})
+	require.Error(t, err)
+}
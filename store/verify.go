@@ -0,0 +1,63 @@
+package store
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Blocks calls fn for every allocated block of the store, including the
+// free blocks, in the order of their addresses.
+func (s *Store) Blocks(fn func(a Address, t BlockType) error) error {
+	nfa := s.nextFreeAddress()
+
+	for a := Address(headerSize + blockHeaderSize); a < nfa; {
+		bits, t, err := s.checkBlock(a)
+		if err != nil {
+			return err
+		}
+
+		err = fn(a, t)
+		if err != nil {
+			return err
+		}
+
+		a += Address(1) << bits
+	}
+
+	return nil
+}
+
+// FreeBlocks calls fn for every block on the free lists.
+// Blocks on the free lists that are not free blocks of the list's size
+// class are reported as ErrCorrupt.
+func (s *Store) FreeBlocks(fn func(a Address) error) error {
+	for bits := minBlockBits; bits < sizeClasses; bits++ {
+		seen := map[Address]struct{}{}
+
+		for a := s.freeListHead(bits); a != NilAddress; {
+			_, isSeen := seen[a]
+			if isSeen {
+				return corruptBlock(a, "free list of size class %d contains a cycle", bits)
+			}
+
+			seen[a] = struct{}{}
+
+			fbits, t, err := s.checkBlock(a)
+			if err != nil {
+				return errors.Wrapf(err, "while walking free list of size class %d", bits)
+			}
+
+			if fbits != bits || t != FreeBlockType {
+				return corruptBlock(a, "block on the free list of size class %d is not a free block of that size", bits)
+			}
+
+			err = fn(a)
+			if err != nil {
+				return err
+			}
+
+			a = s.nextFreeBlock(a)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package store
+
+import (
+	"io"
+)
+
+// writeChunkSize is the maximum number of bytes passed to a single Write
+// by WriteTo.
+const writeChunkSize = 1024 * 1024
+
+// WriteTo writes the header and all allocated blocks of the store to w.
+// The written bytes are a store file that can be opened with Open.
+// Changes committed while WriteTo is running make the written copy
+// inconsistent, writing a snapshot of the store avoids that.
+func (s *Store) WriteTo(w io.Writer) (int64, error) {
+	end := s.nextFreeAddress().UInt64()
+
+	var written int64
+
+	for from := uint64(0); from < end; from += writeChunkSize {
+		to := from + writeChunkSize
+		if to > end {
+			to = end
+		}
+
+		n, err := w.Write(s.mm[from:to])
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
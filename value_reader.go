@@ -0,0 +1,87 @@
+package l5db
+
+import (
+	"io"
+)
+
+// ValueReader reads a value.
+// Besides reading the value in order it supports random access with ReadAt
+// and Seek.
+type ValueReader interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+	io.WriterTo
+}
+
+// ValueReadCloser is a ValueReader that has to be closed after use.
+type ValueReadCloser interface {
+	ValueReader
+	io.Closer
+}
+
+// GetReader returns a reader of the value at the path.
+// The reader holds a read transaction until it is closed, so it reads the
+// value as it was when GetReader was called, also when the value is changed
+// by write transactions committed in the meantime.
+func (d *DB) GetReader(path string) (ValueReadCloser, error) {
+	tx, err := d.NewReadTransaction()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := tx.GetReader(path)
+	if err != nil {
+		tx.Close()
+		return nil, err
+	}
+
+	return &snapshotReader{
+		r:  r,
+		tx: tx,
+	}, nil
+}
+
+// snapshotReader reads a value within its own read transaction.
+type snapshotReader struct {
+	r  ValueReader
+	tx *ReadTransaction
+}
+
+func (s *snapshotReader) Read(p []byte) (int, error) {
+	if s.tx.finished {
+		return 0, ErrTransactionFinished
+	}
+
+	return s.r.Read(p)
+}
+
+func (s *snapshotReader) ReadAt(p []byte, off int64) (int, error) {
+	if s.tx.finished {
+		return 0, ErrTransactionFinished
+	}
+
+	return s.r.ReadAt(p, off)
+}
+
+func (s *snapshotReader) Seek(offset int64, whence int) (int64, error) {
+	if s.tx.finished {
+		return 0, ErrTransactionFinished
+	}
+
+	return s.r.Seek(offset, whence)
+}
+
+// WriteTo writes the rest of the value to w without copying it into
+// intermediate buffers.
+func (s *snapshotReader) WriteTo(w io.Writer) (int64, error) {
+	if s.tx.finished {
+		return 0, ErrTransactionFinished
+	}
+
+	return s.r.WriteTo(w)
+}
+
+func (s *snapshotReader) Close() error {
+	return s.tx.Close()
+}
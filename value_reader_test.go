@@ -0,0 +1,95 @@
+package l5db_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/draganm/l5db"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetReader(t *testing.T) {
+	td, cleanup := createTempDir(t)
+	defer cleanup()
+
+	db, err := l5db.Open(td)
+	require.NoError(t, err)
+	defer db.Close()
+
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+
+	err = db.Put("abc", data)
+	require.NoError(t, err)
+
+	r, err := db.GetReader("abc")
+	require.NoError(t, err)
+
+	tx, err := db.NewWriteTransaction(context.Background())
+	require.NoError(t, err)
+
+	err = tx.Put("abc", []byte{1, 2, 3})
+	require.NoError(t, err)
+
+	err = tx.Commit()
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	_, err = io.Copy(buf, r)
+	require.NoError(t, err)
+	require.Equal(t, data, buf.Bytes())
+
+	err = r.Close()
+	require.NoError(t, err)
+
+	_, err = r.Read(make([]byte, 1))
+	require.True(t, errors.Is(err, l5db.ErrTransactionFinished))
+
+	_, err = db.GetReader("def")
+	require.Error(t, err)
+
+	r, err = db.GetReader("abc")
+	require.NoError(t, err)
+	defer r.Close()
+
+	d := make([]byte, 3)
+	_, err = io.ReadFull(r, d)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, d)
+}
+
+func TestGetReaderRandomAccess(t *testing.T) {
+	td, cleanup := createTempDir(t)
+	defer cleanup()
+
+	db, err := l5db.Open(td)
+	require.NoError(t, err)
+	defer db.Close()
+
+	data := make([]byte, 100000)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	err = db.Put("abc", data)
+	require.NoError(t, err)
+
+	r, err := db.GetReader("abc")
+	require.NoError(t, err)
+	defer r.Close()
+
+	p := make([]byte, 1000)
+	_, err = r.ReadAt(p, 70000)
+	require.NoError(t, err)
+	require.Equal(t, data[70000:71000], p)
+
+	_, err = r.Seek(-10, io.SeekEnd)
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	_, err = io.Copy(buf, r)
+	require.NoError(t, err)
+	require.Equal(t, data[len(data)-10:], buf.Bytes())
+}
@@ -0,0 +1,249 @@
+package l5db
+
+import (
+	"context"
+	serrors "errors"
+	"io"
+
+	"github.com/draganm/l5db/btree"
+	"github.com/draganm/l5db/dbpath"
+	"github.com/draganm/l5db/sequential"
+	"github.com/draganm/l5db/store"
+	"github.com/pkg/errors"
+)
+
+var ErrWriterClosed = serrors.New("writer is closed")
+
+var ErrWritersOpen = serrors.New("transaction has open writers")
+
+// ValueWriter streams data into a new value.
+// The value is stored at its path, replacing the previous value, only when
+// the writer is closed successfully.
+type ValueWriter struct {
+	tx         *WriteTransaction
+	parsedPath []string
+	addr       store.Address
+	// ownsTx is set for writers created by DB.CreateWriter, they commit
+	// or roll back the transaction when they are closed or aborted.
+	ownsTx bool
+	err    error
+	closed bool
+}
+
+// CreateWriter returns a writer of a new value at the path.
+// The value is stored in the transaction when the writer is closed, the
+// writer must be closed or aborted before the transaction is committed,
+// committing with open writers fails with ErrWritersOpen. Writers of a
+// transaction that was rolled back fail with ErrTransactionFinished.
+func (d *WriteTransaction) CreateWriter(pth string) (*ValueWriter, error) {
+	if d.finished {
+		return nil, ErrTransactionFinished
+	}
+
+	parsedPath, err := dbpath.Split(pth)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while parsing dbpath %q", pth)
+	}
+
+	if len(parsedPath) == 0 {
+		return nil, errors.New("trying to put data into root")
+	}
+
+	_, err = getAddressOfParent(d.s, d.s.GetRootAddress(), parsedPath)
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := sequential.CreateEmpty(d.s, d.db.options.SequentialBlockSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "while creating empty sequential data")
+	}
+
+	d.openWriters++
+
+	return &ValueWriter{
+		tx:         d,
+		parsedPath: parsedPath,
+		addr:       addr,
+	}, nil
+}
+
+// PutReader stores all data read from r as the value at the path.
+func (d *WriteTransaction) PutReader(pth string, r io.Reader) error {
+	if d.finished {
+		return ErrTransactionFinished
+	}
+
+	w, err := d.CreateWriter(pth)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, r)
+	if err != nil {
+		if w.closed {
+			// failed writes abort the writer
+			return err
+		}
+
+		abortErr := w.Abort()
+		if abortErr != nil {
+			return errors.Wrapf(err, "abort failed: %s", abortErr)
+		}
+		return err
+	}
+
+	return w.Close()
+}
+
+// CreateWriter returns a writer of a new value at the path.
+// The writer holds its own write transaction, the value is stored and
+// committed when the writer is closed. Other write transactions and
+// direct modifications wait until the writer is closed or aborted.
+func (d *DB) CreateWriter(pth string) (*ValueWriter, error) {
+	tx, err := d.NewWriteTransaction(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := tx.CreateWriter(pth)
+	if err != nil {
+		rbErr := tx.Rollback()
+		if rbErr != nil {
+			return nil, errors.Wrapf(err, "rollback failed: %s", rbErr)
+		}
+		return nil, err
+	}
+
+	w.ownsTx = true
+
+	return w, nil
+}
+
+// PutReader stores all data read from r as the value at the path.
+func (d *DB) PutReader(pth string, r io.Reader) error {
+	return d.update(func(tx *WriteTransaction) error {
+		return tx.PutReader(pth, r)
+	})
+}
+
+// Write appends p to the value.
+// If writing fails, the writer is aborted.
+func (w *ValueWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, ErrWriterClosed
+	}
+
+	if w.tx.finished {
+		return 0, ErrTransactionFinished
+	}
+
+	err := sequential.Append(w.tx.s, w.addr, p)
+	if err != nil {
+		w.err = err
+		abortErr := w.Abort()
+		if abortErr != nil {
+			return 0, errors.Wrapf(err, "abort failed: %s", abortErr)
+		}
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Close stores the written value at its path.
+// If the writer was created by DB.CreateWriter, its transaction is
+// committed.
+func (w *ValueWriter) Close() error {
+	if w.closed {
+		if w.err != nil {
+			return w.err
+		}
+		return ErrWriterClosed
+	}
+
+	if w.tx.finished {
+		return ErrTransactionFinished
+	}
+
+	w.closed = true
+	w.tx.openWriters--
+
+	err := w.store()
+	if err != nil {
+		if w.ownsTx {
+			rbErr := w.tx.Rollback()
+			if rbErr != nil {
+				return errors.Wrapf(err, "rollback failed: %s", rbErr)
+			}
+		}
+		return err
+	}
+
+	if w.ownsTx {
+		return w.tx.Commit()
+	}
+
+	return nil
+}
+
+// store puts the written value into its map, replacing the previous value.
+// If the value can't be put into the map, it is freed.
+func (w *ValueWriter) store() error {
+	ma, err := getAddressOfParent(w.tx.s, w.tx.s.GetRootAddress(), w.parsedPath)
+	if err != nil {
+		return w.discard(err)
+	}
+
+	key := []byte(w.parsedPath[len(w.parsedPath)-1])
+
+	old, err := btree.Get(w.tx.s, ma, key)
+	if err != nil && err != btree.ErrNotFound {
+		return w.discard(err)
+	}
+
+	hasOld := err == nil
+
+	err = btree.Put(w.tx.s, ma, key, w.addr)
+	if err != nil {
+		return w.discard(err)
+	}
+
+	if !hasOld {
+		return nil
+	}
+
+	return freeValue(w.tx.s, old)
+}
+
+// discard frees the written value that couldn't be stored.
+func (w *ValueWriter) discard(err error) error {
+	freeErr := sequential.Free(w.tx.s, w.addr)
+	if freeErr != nil {
+		return errors.Wrapf(err, "free failed: %s", freeErr)
+	}
+
+	return err
+}
+
+// Abort discards the written data without changing the value at the path.
+// If the writer was created by DB.CreateWriter, its transaction is rolled
+// back.
+func (w *ValueWriter) Abort() error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+
+	if w.tx.finished {
+		return ErrTransactionFinished
+	}
+
+	w.closed = true
+	w.tx.openWriters--
+
+	if w.ownsTx {
+		return w.tx.Rollback()
+	}
+
+	return sequential.Free(w.tx.s, w.addr)
+}
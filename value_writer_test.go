@@ -0,0 +1,231 @@
+package l5db_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/draganm/l5db"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateWriter(t *testing.T) {
+	td, cleanup := createTempDir(t)
+	defer cleanup()
+
+	db, err := l5db.Open(td)
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Put("abc", []byte{1})
+	require.NoError(t, err)
+
+	w, err := db.CreateWriter("abc")
+	require.NoError(t, err)
+
+	chunk := bytes.Repeat([]byte{1, 2, 3, 4}, 10000)
+
+	for i := 0; i < 10; i++ {
+		_, err = w.Write(chunk)
+		require.NoError(t, err)
+	}
+
+	d, err := db.Get("abc")
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, d)
+
+	err = w.Close()
+	require.NoError(t, err)
+
+	d, err = db.Get("abc")
+	require.NoError(t, err)
+	require.Equal(t, bytes.Repeat(chunk, 10), d)
+
+	err = w.Close()
+	require.True(t, errors.Is(err, l5db.ErrWriterClosed))
+
+	err = db.Verify()
+	require.NoError(t, err)
+}
+
+func TestAbortWriter(t *testing.T) {
+	td, cleanup := createTempDir(t)
+	defer cleanup()
+
+	db, err := l5db.Open(td)
+	require.NoError(t, err)
+	defer db.Close()
+
+	w, err := db.CreateWriter("abc")
+	require.NoError(t, err)
+
+	_, err = w.Write([]byte{1, 2, 3})
+	require.NoError(t, err)
+
+	err = w.Abort()
+	require.NoError(t, err)
+
+	ex, err := db.Exists("abc")
+	require.NoError(t, err)
+	require.False(t, ex)
+
+	tx, err := db.NewWriteTransaction(context.Background())
+	require.NoError(t, err)
+
+	w, err = tx.CreateWriter("abc")
+	require.NoError(t, err)
+
+	_, err = w.Write([]byte{1, 2, 3})
+	require.NoError(t, err)
+
+	err = w.Abort()
+	require.NoError(t, err)
+
+	err = tx.Commit()
+	require.NoError(t, err)
+
+	err = db.Verify()
+	require.NoError(t, err)
+}
+
+func TestCommitWithOpenWriter(t *testing.T) {
+	td, cleanup := createTempDir(t)
+	defer cleanup()
+
+	db, err := l5db.Open(td)
+	require.NoError(t, err)
+	defer db.Close()
+
+	tx, err := db.NewWriteTransaction(context.Background())
+	require.NoError(t, err)
+
+	w, err := tx.CreateWriter("abc")
+	require.NoError(t, err)
+
+	_, err = w.Write([]byte{1, 2, 3})
+	require.NoError(t, err)
+
+	err = tx.Commit()
+	require.True(t, errors.Is(err, l5db.ErrWritersOpen))
+
+	err = w.Close()
+	require.NoError(t, err)
+
+	err = tx.Commit()
+	require.NoError(t, err)
+
+	d, err := db.Get("abc")
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, d)
+
+	err = db.Verify()
+	require.NoError(t, err)
+}
+
+func TestWriterOfRolledBackTransaction(t *testing.T) {
+	td, cleanup := createTempDir(t)
+	defer cleanup()
+
+	db, err := l5db.Open(td)
+	require.NoError(t, err)
+	defer db.Close()
+
+	tx, err := db.NewWriteTransaction(context.Background())
+	require.NoError(t, err)
+
+	w, err := tx.CreateWriter("abc")
+	require.NoError(t, err)
+
+	err = tx.Rollback()
+	require.NoError(t, err)
+
+	_, err = w.Write([]byte{1, 2, 3})
+	require.True(t, errors.Is(err, l5db.ErrTransactionFinished))
+
+	err = w.Close()
+	require.True(t, errors.Is(err, l5db.ErrTransactionFinished))
+
+	err = w.Abort()
+	require.True(t, errors.Is(err, l5db.ErrTransactionFinished))
+
+	ex, err := db.Exists("abc")
+	require.NoError(t, err)
+	require.False(t, ex)
+}
+
+func TestCloseWriterWithoutParentMap(t *testing.T) {
+	td, cleanup := createTempDir(t)
+	defer cleanup()
+
+	db, err := l5db.Open(td)
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.CreateMap("m")
+	require.NoError(t, err)
+
+	tx, err := db.NewWriteTransaction(context.Background())
+	require.NoError(t, err)
+
+	w, err := tx.CreateWriter("m/abc")
+	require.NoError(t, err)
+
+	_, err = w.Write(bytes.Repeat([]byte{1, 2, 3}, 20000))
+	require.NoError(t, err)
+
+	err = tx.Delete("m")
+	require.NoError(t, err)
+
+	err = w.Close()
+	require.Error(t, err)
+
+	err = tx.Commit()
+	require.NoError(t, err)
+
+	err = db.Verify()
+	require.NoError(t, err)
+}
+
+type failingReader struct {
+	r io.Reader
+}
+
+var errReaderFailed = errors.New("reader failed")
+
+func (f failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err == io.EOF {
+		return n, errReaderFailed
+	}
+	return n, err
+}
+
+func TestPutReader(t *testing.T) {
+	td, cleanup := createTempDir(t)
+	defer cleanup()
+
+	db, err := l5db.Open(td)
+	require.NoError(t, err)
+	defer db.Close()
+
+	data := bytes.Repeat([]byte("0123456789"), 100000)
+
+	err = db.PutReader("abc", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	d, err := db.Get("abc")
+	require.NoError(t, err)
+	require.Equal(t, data, d)
+
+	err = db.PutReader("abc", failingReader{r: bytes.NewReader([]byte{1, 2, 3})})
+	require.True(t, errors.Is(err, errReaderFailed))
+
+	d, err = db.Get("abc")
+	require.NoError(t, err)
+	require.Equal(t, data, d)
+
+	err = db.Verify()
+	require.NoError(t, err)
+}
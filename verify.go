@@ -0,0 +1,129 @@
+package l5db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/draganm/l5db/btree"
+	"github.com/draganm/l5db/dbpath"
+	"github.com/draganm/l5db/sequential"
+	"github.com/draganm/l5db/store"
+	"github.com/pkg/errors"
+)
+
+// VerificationError is returned by Verify when the database is not
+// consistent.
+type VerificationError struct {
+	Problems []string
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("database is inconsistent:\n%s", strings.Join(e.Problems, "\n"))
+}
+
+const rootPath = "/"
+
+// freeListPath is used instead of a path for blocks on the free lists.
+const freeListPath = "free list"
+
+// Verify checks the consistency of the last committed state of the
+// database. It walks all maps and values and checks the structure of their
+// btrees and data block chains, the free lists and that every allocated
+// block is either referenced exactly once or on a free list.
+// All found problems are returned as a *VerificationError.
+func (d *DB) Verify() error {
+	s, err := d.st.Snapshot()
+	if err != nil {
+		return errors.Wrap(err, "while creating snapshot for verification")
+	}
+
+	defer s.Close()
+
+	v := &verifier{
+		m:    s,
+		refs: map[store.Address]string{},
+	}
+
+	v.verifyValue(rootPath, s.GetRootAddress())
+
+	err = s.FreeBlocks(v.visitBlock(freeListPath))
+	if err != nil {
+		v.addProblem(freeListPath, err)
+	}
+
+	err = s.Blocks(func(a store.Address, t store.BlockType) error {
+		_, isReferenced := v.refs[a]
+		if !isReferenced {
+			v.problems = append(v.problems, fmt.Sprintf("block %d of type %d is not reachable", a, t))
+		}
+		return nil
+	})
+	if err != nil {
+		v.problems = append(v.problems, fmt.Sprintf("while walking blocks: %s", err))
+	}
+
+	if len(v.problems) > 0 {
+		return &VerificationError{Problems: v.problems}
+	}
+
+	return nil
+}
+
+type verifier struct {
+	m        store.Memory
+	refs     map[store.Address]string
+	problems []string
+}
+
+func (v *verifier) addProblem(pth string, err error) {
+	v.problems = append(v.problems, fmt.Sprintf("%s: %s", pth, err))
+}
+
+// visitBlock returns a function recording that the block is referenced
+// from the path. It returns an error if the block is already referenced.
+func (v *verifier) visitBlock(pth string) func(store.Address) error {
+	return func(a store.Address) error {
+		other, isReferenced := v.refs[a]
+		if isReferenced {
+			return errors.Errorf("block %d is also referenced from %s", a, other)
+		}
+
+		v.refs[a] = pth
+		return nil
+	}
+}
+
+// verifyValue verifies the map or value at the path and records all found
+// problems.
+func (v *verifier) verifyValue(pth string, a store.Address) {
+	err := v.verifyValueBlocks(pth, a)
+	if err != nil {
+		v.addProblem(pth, err)
+	}
+}
+
+func (v *verifier) verifyValueBlocks(pth string, a store.Address) error {
+	_, t, err := v.m.GetBlock(a)
+	if err != nil {
+		return errors.Wrap(err, "while getting value block")
+	}
+
+	switch t {
+	case store.BTreeMetaBlockType:
+		return btree.Verify(v.m, a, v.visitBlock(pth), func(key []byte, value store.Address) error {
+			v.verifyValue(childPath(pth, string(key)), value)
+			return nil
+		})
+	case store.SequentialMetaBlockType, store.SequentialInlineBlockType:
+		return sequential.Verify(v.m, a, v.visitBlock(pth))
+	default:
+		return errors.Errorf("unsupported value block type %d", t)
+	}
+}
+
+func childPath(pth, name string) string {
+	if pth == rootPath {
+		return dbpath.EscapePart(name)
+	}
+	return pth + dbpath.Separator + dbpath.EscapePart(name)
+}
@@ -0,0 +1,67 @@
+package l5db_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/draganm/l5db"
+	"github.com/draganm/l5db/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	td, cleanup := createTempDir(t)
+	defer cleanup()
+
+	db, err := l5db.Open(td)
+	require.NoError(t, err)
+
+	err = db.CreateMap("m")
+	require.NoError(t, err)
+
+	for _, k := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		err = db.Put("m/"+k, []byte(k))
+		require.NoError(t, err)
+	}
+
+	err = db.Put("m/a", []byte("overwritten"))
+	require.NoError(t, err)
+
+	err = db.Delete("m/b")
+	require.NoError(t, err)
+
+	err = db.Verify()
+	require.NoError(t, err)
+
+	err = db.Close()
+	require.NoError(t, err)
+
+	st, err := store.Open(td, 1024*1024*1024)
+	require.NoError(t, err)
+
+	leaked, _, err := st.Allocate(10, store.SequentialMetaBlockType)
+	require.NoError(t, err)
+	err = st.Touch(leaked)
+	require.NoError(t, err)
+
+	rootMeta, _, err := st.GetBlock(st.GetRootAddress())
+	require.NoError(t, err)
+	binary.LittleEndian.PutUint64(rootMeta, 42)
+	err = st.Touch(st.GetRootAddress())
+	require.NoError(t, err)
+
+	err = st.Close()
+	require.NoError(t, err)
+
+	db, err = l5db.Open(td)
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = db.Verify()
+	var ve *l5db.VerificationError
+	require.True(t, errors.As(err, &ve))
+	require.Len(t, ve.Problems, 2)
+	require.Contains(t, ve.Problems[0], "has count 42, but contains 1 keys")
+	require.Contains(t, ve.Problems[1], "is not reachable")
+}
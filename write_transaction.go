@@ -1,6 +1,7 @@
 package l5db
 
 import (
+	serrors "errors"
 	"io/ioutil"
 
 	"github.com/draganm/l5db/btree"
@@ -10,27 +11,67 @@ import (
 	"github.com/pkg/errors"
 )
 
+var ErrTransactionFinished = serrors.New("transaction is already committed or rolled back")
+
 type WriteTransaction struct {
-	s *store.Store
+	db       *DB
+	s        *store.Store
+	finished bool
+	// openWriters counts the writers created by CreateWriter that are
+	// neither closed nor aborted.
+	openWriters int
 }
 
-func (d *WriteTransaction) getAddressOfParent(parsedPath []string) (store.Address, error) {
+// Commit makes all changes of the transaction visible to the database.
+// It fails with ErrWritersOpen, leaving the transaction open, while
+// writers created by CreateWriter are not closed or aborted.
+func (d *WriteTransaction) Commit() error {
+	if d.finished {
+		return ErrTransactionFinished
+	}
+
+	if d.openWriters > 0 {
+		return ErrWritersOpen
+	}
 
-	ma := d.s.GetRootAddress()
+	defer d.finish()
 
-	for _, pe := range parsedPath[:len(parsedPath)-1] {
-		var err error
-		ma, err = btree.Get(d.s, ma, []byte(pe))
-		if err != nil {
-			return store.NilAddress, errors.Wrap(err, "while creating map")
-		}
+	d.db.mu.Lock()
+	defer d.db.mu.Unlock()
+
+	err := d.s.Commit()
+	if err != nil {
+		return errors.Wrap(err, "while committing write transaction")
+	}
+
+	return nil
+}
+
+// Rollback discards all changes of the transaction.
+func (d *WriteTransaction) Rollback() error {
+	if d.finished {
+		return ErrTransactionFinished
 	}
 
-	return ma, nil
+	defer d.finish()
 
+	err := d.s.Rollback()
+	if err != nil {
+		return errors.Wrap(err, "while rolling back write transaction")
+	}
+
+	return nil
+}
+
+func (d *WriteTransaction) finish() {
+	d.finished = true
+	<-d.db.writeLock
 }
 
 func (d *WriteTransaction) CreateMap(pth string) error {
+	if d.finished {
+		return ErrTransactionFinished
+	}
 
 	parsedPath, err := dbpath.Split(pth)
 	if err != nil {
@@ -43,117 +84,380 @@ func (d *WriteTransaction) CreateMap(pth string) error {
 
 	lastKey := parsedPath[len(parsedPath)-1]
 
-	ma, err := d.getAddressOfParent(parsedPath)
+	ma, err := getAddressOfParent(d.s, d.s.GetRootAddress(), parsedPath)
 	if err != nil {
 		return err
 	}
 
-	empty, err := btree.CreateEmptyBTree(d.s, 5, 32)
+	empty, err := btree.CreateEmptyBTree(d.s, d.db.options.BTreeOrder, d.db.options.KeySizeHint)
 	if err != nil {
 		return errors.Wrap(err, "while creating empty btree")
 	}
 
-	return btree.Put(d.s, ma, []byte(lastKey), empty)
+	return replaceValue(d.s, ma, []byte(lastKey), empty)
+
+}
+
+func (d *WriteTransaction) Size(path string) (uint64, error) {
+	if d.finished {
+		return 0, ErrTransactionFinished
+	}
 
+	return size(d.s, d.s.GetRootAddress(), path)
 }
 
-func (d *WriteTransaction) getAddressOf(pth string) (store.Address, error) {
+func (d *WriteTransaction) Exists(path string) (bool, error) {
+	if d.finished {
+		return false, ErrTransactionFinished
+	}
+
+	return exists(d.s, d.s.GetRootAddress(), path)
+}
+
+func (d *WriteTransaction) Put(pth string, data []byte) error {
+	if d.finished {
+		return ErrTransactionFinished
+	}
+
 	parsedPath, err := dbpath.Split(pth)
 	if err != nil {
-		return store.NilAddress, errors.Wrapf(err, "while parsing dbpath %q", pth)
+		return errors.Wrapf(err, "while parsing dbpath %q", pth)
+	}
+
+	if len(pth) == 0 {
+		return errors.New("trying to put data into root")
+	}
+
+	lastKey := parsedPath[len(parsedPath)-1]
+
+	ma, err := getAddressOfParent(d.s, d.s.GetRootAddress(), parsedPath)
+	if err != nil {
+		return err
 	}
 
-	ma := d.s.GetRootAddress()
+	return d.putValue(ma, []byte(lastKey), data)
+}
 
-	for _, pe := range parsedPath {
-		var err error
-		ma, err = btree.Get(d.s, ma, []byte(pe))
+// putValue stores the data as the value under the key of the map.
+func (d *WriteTransaction) putValue(ma store.Address, key []byte, data []byte) error {
+	if len(data) <= int(d.db.options.SequentialBlockSize) {
+		// values fitting into a single data block are stored inline
+		va, err := sequential.CreateInline(d.s, data)
 		if err != nil {
-			return store.NilAddress, errors.Wrap(err, "while getting element")
+			return errors.Wrap(err, "while creating inline value")
 		}
+
+		return replaceValue(d.s, ma, key, va)
+	}
+
+	empty, err := sequential.CreateEmpty(d.s, d.db.options.SequentialBlockSize)
+	if err != nil {
+		return errors.Wrap(err, "while creating empty sequential data")
 	}
 
-	return ma, nil
+	err = sequential.Append(d.s, empty, data)
+	if err != nil {
+		return errors.Wrap(err, "while appending sequential data")
+	}
 
+	return replaceValue(d.s, ma, key, empty)
 }
 
-func (d *WriteTransaction) Size(path string) (uint64, error) {
+// Append appends the data to the value at the path, creating the value if
+// it doesn't exist.
+func (d *WriteTransaction) Append(pth string, data []byte) error {
+	if d.finished {
+		return ErrTransactionFinished
+	}
+
+	parsedPath, err := dbpath.Split(pth)
+	if err != nil {
+		return errors.Wrapf(err, "while parsing dbpath %q", pth)
+	}
+
+	if len(parsedPath) == 0 {
+		return errors.New("trying to append data to root")
+	}
+
+	lastKey := parsedPath[len(parsedPath)-1]
+
+	ma, err := getAddressOfParent(d.s, d.s.GetRootAddress(), parsedPath)
+	if err != nil {
+		return err
+	}
+
+	va, err := btree.Get(d.s, ma, []byte(lastKey))
+	if err == btree.ErrNotFound {
+		return d.putValue(ma, []byte(lastKey), data)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "while getting %q", pth)
+	}
+
+	isMap, err := isMapAddress(d.s, va)
+	if err != nil {
+		return err
+	}
+
+	if isMap {
+		return errors.Errorf("%q is a map", pth)
+	}
+
+	isInline, err := sequential.IsInline(d.s, va)
+	if err != nil {
+		return err
+	}
+
+	if !isInline {
+		blockSize, err := sequential.BlockSize(d.s, va)
+		if err != nil {
+			return err
+		}
+
+		if blockSize >= d.db.options.SequentialBlockSize {
+			err = sequential.Append(d.s, va, data)
+			if err != nil {
+				return errors.Wrap(err, "while appending sequential data")
+			}
+
+			return nil
+		}
+	}
+
+	// inline values and values with smaller blocks are rewritten, so that
+	// appending doesn't create a chain of small blocks
+	r, err := sequential.Reader(d.s, va)
+	if err != nil {
+		return err
+	}
 
-	ta, err := d.getAddressOf(path)
+	old, err := ioutil.ReadAll(r)
 	if err != nil {
-		return 0, err
+		return errors.Wrapf(err, "while reading %q", pth)
 	}
 
-	return btree.Count(d.s, ta)
+	return d.putValue(ma, []byte(lastKey), append(old, data...))
 }
 
-func (d *WriteTransaction) Exists(path string) (bool, error) {
+// Truncate shrinks the value at the path to n bytes.
+func (d *WriteTransaction) Truncate(pth string, n uint64) error {
+	if d.finished {
+		return ErrTransactionFinished
+	}
 
-	a, err := d.getAddressOf(path)
+	ma, key, va, err := d.valueAddress(pth)
+	if err != nil {
+		return err
+	}
 
-	cause := errors.Cause(err)
+	data, isInline, err := d.inlineData(va)
+	if err != nil {
+		return err
+	}
 
-	if cause == btree.ErrNotFound {
-		return false, nil
+	if isInline {
+		if n > uint64(len(data)) {
+			return errors.Errorf("can't truncate data of size %d to larger size %d", len(data), n)
+		}
+
+		return d.putValue(ma, key, data[:n])
 	}
 
+	err = sequential.Truncate(d.s, va, n)
 	if err != nil {
-		return false, err
+		return errors.Wrapf(err, "while truncating %q", pth)
 	}
 
-	return a != store.NilAddress, nil
+	return nil
 }
 
-func (d *WriteTransaction) Put(pth string, data []byte) error {
+// WriteAt overwrites the value at the path with the data starting at the
+// offset. Data past the end of the value is appended, the offset must not
+// be larger than the size of the value.
+func (d *WriteTransaction) WriteAt(pth string, off uint64, data []byte) error {
+	if d.finished {
+		return ErrTransactionFinished
+	}
+
+	ma, key, va, err := d.valueAddress(pth)
+	if err != nil {
+		return err
+	}
+
+	old, isInline, err := d.inlineData(va)
+	if err != nil {
+		return err
+	}
 
+	if isInline {
+		if off > uint64(len(old)) {
+			return errors.Errorf("offset %d is past the end of data of size %d", off, len(old))
+		}
+
+		size := len(old)
+		if int(off)+len(data) > size {
+			size = int(off) + len(data)
+		}
+
+		// the value stays inline if the result still fits into a block
+		nd := make([]byte, size)
+		copy(nd, old)
+		copy(nd[off:], data)
+
+		return d.putValue(ma, key, nd)
+	}
+
+	err = sequential.WriteAt(d.s, va, off, data)
+	if err != nil {
+		return errors.Wrapf(err, "while writing to %q", pth)
+	}
+
+	return nil
+}
+
+// valueAddress returns the address of the map containing the value at the
+// path, the key of the value in the map and the address of the value.
+func (d *WriteTransaction) valueAddress(pth string) (store.Address, []byte, store.Address, error) {
 	parsedPath, err := dbpath.Split(pth)
 	if err != nil {
-		return errors.Wrapf(err, "while parsing dbpath %q", pth)
+		return store.NilAddress, nil, store.NilAddress, errors.Wrapf(err, "while parsing dbpath %q", pth)
 	}
 
-	if len(pth) == 0 {
-		return errors.New("trying to put data into root")
+	if len(parsedPath) == 0 {
+		return store.NilAddress, nil, store.NilAddress, errors.New("root is not a value")
 	}
 
-	lastKey := parsedPath[len(parsedPath)-1]
+	key := []byte(parsedPath[len(parsedPath)-1])
 
-	ma, err := d.getAddressOfParent(parsedPath)
+	ma, err := getAddressOfParent(d.s, d.s.GetRootAddress(), parsedPath)
 	if err != nil {
-		return err
+		return store.NilAddress, nil, store.NilAddress, err
+	}
+
+	va, err := btree.Get(d.s, ma, key)
+	if err != nil {
+		return store.NilAddress, nil, store.NilAddress, errors.Wrapf(err, "while getting %q", pth)
+	}
+
+	isMap, err := isMapAddress(d.s, va)
+	if err != nil {
+		return store.NilAddress, nil, store.NilAddress, err
+	}
+
+	if isMap {
+		return store.NilAddress, nil, store.NilAddress, errors.Errorf("%q is a map", pth)
 	}
 
-	blockSize := 16 * 1024
+	return ma, key, va, nil
+}
+
+// inlineData returns a copy of the data of the value if it is an inline
+// value.
+func (d *WriteTransaction) inlineData(va store.Address) ([]byte, bool, error) {
+	isInline, err := sequential.IsInline(d.s, va)
+	if err != nil {
+		return nil, false, err
+	}
 
-	if len(data) < blockSize {
-		blockSize = len(data)
+	if !isInline {
+		return nil, false, nil
 	}
 
-	empty, err := sequential.CreateEmpty(d.s, uint16(blockSize))
+	r, err := sequential.Reader(d.s, va)
 	if err != nil {
-		return errors.Wrap(err, "while creating empty sequential data")
+		return nil, false, err
 	}
 
-	err = sequential.Append(d.s, empty, data)
+	data, err := ioutil.ReadAll(r)
 	if err != nil {
-		return errors.Wrap(err, "while appending sequential data")
+		return nil, false, errors.Wrap(err, "while reading inline value")
 	}
 
-	return btree.Put(d.s, ma, []byte(lastKey), empty)
+	return data, true, nil
 }
 
 func (d *WriteTransaction) Get(path string) ([]byte, error) {
+	if d.finished {
+		return nil, ErrTransactionFinished
+	}
 
-	a, err := d.getAddressOf(path)
+	return get(d.s, d.s.GetRootAddress(), path)
+}
 
+// GetReader returns a reader of the value at the path.
+// The reader must not be used after the transaction is modified.
+func (d *WriteTransaction) GetReader(path string) (ValueReader, error) {
+	if d.finished {
+		return nil, ErrTransactionFinished
+	}
+
+	return getReader(d.s, d.s.GetRootAddress(), path)
+}
+
+// Iterator returns an iterator over the children of the map at the path.
+// The iterator must not be used after the transaction is modified.
+func (d *WriteTransaction) Iterator(path string) (*Iterator, error) {
+	if d.finished {
+		return nil, ErrTransactionFinished
+	}
+
+	return newIterator(d.s, d.s.GetRootAddress(), path)
+}
+
+func (d *WriteTransaction) List(path string) ([]ListEntry, error) {
+	if d.finished {
+		return nil, ErrTransactionFinished
+	}
+
+	return list(d.s, d.s.GetRootAddress(), path)
+}
+
+// Scan returns the children of the map at the path selected by the options.
+func (d *WriteTransaction) Scan(path string, o ScanOptions) ([]ListEntry, error) {
+	if d.finished {
+		return nil, ErrTransactionFinished
+	}
+
+	return scan(d.s, d.s.GetRootAddress(), path, o)
+}
+
+// Delete removes the value or the whole map stored at the path.
+func (d *WriteTransaction) Delete(pth string) error {
+	if d.finished {
+		return ErrTransactionFinished
+	}
+
+	parsedPath, err := dbpath.Split(pth)
+	if err != nil {
+		return errors.Wrapf(err, "while parsing dbpath %q", pth)
+	}
+
+	if len(parsedPath) == 0 {
+		return errors.New("trying to delete root")
+	}
+
+	lastKey := parsedPath[len(parsedPath)-1]
+
+	ma, err := getAddressOfParent(d.s, d.s.GetRootAddress(), parsedPath)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	r, err := sequential.Reader(d.s, a)
+	old, err := btree.Get(d.s, ma, []byte(lastKey))
 	if err != nil {
-		return nil, err
+		return errors.Wrapf(err, "while getting %q", pth)
 	}
 
-	return ioutil.ReadAll(r)
+	err = btree.Delete(d.s, ma, []byte(lastKey))
+	if err != nil {
+		return errors.Wrapf(err, "while deleting %q", pth)
+	}
+
+	err = freeValue(d.s, old)
+	if err != nil {
+		return errors.Wrapf(err, "while freeing %q", pth)
+	}
 
+	return nil
 }
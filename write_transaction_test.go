@@ -1,8 +1,13 @@
 package l5db_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/draganm/l5db"
 	"github.com/stretchr/testify/require"
 )
 
@@ -17,7 +22,7 @@ func TestWriteTransactionIsolation(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, ex)
 
-	wtx, err := db.NewWriteTransaction()
+	wtx, err := db.NewWriteTransaction(context.Background())
 	require.NoError(t, err)
 
 	err = wtx.CreateMap("def")
@@ -36,3 +41,240 @@ func TestWriteTransactionIsolation(t *testing.T) {
 	require.False(t, ex)
 
 }
+
+func TestWriteTransactionCommit(t *testing.T) {
+	db, cleanup := createEmptyDB(t)
+	defer cleanup()
+
+	wtx, err := db.NewWriteTransaction(context.Background())
+	require.NoError(t, err)
+
+	err = wtx.CreateMap("abc")
+	require.NoError(t, err)
+
+	err = wtx.Put("abc/def", []byte{1, 2, 3})
+	require.NoError(t, err)
+
+	err = wtx.Commit()
+	require.NoError(t, err)
+
+	ex, err := db.Exists("abc")
+	require.NoError(t, err)
+	require.True(t, ex)
+
+	d, err := db.Get("abc/def")
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, d)
+
+	err = db.Put("abc/ghi", []byte{4, 5, 6})
+	require.NoError(t, err)
+
+	abcSize, err := db.Size("abc")
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), abcSize)
+
+}
+
+func TestWriteTransactionCommitIsDurable(t *testing.T) {
+	td, cleanup := createTempDir(t)
+	defer cleanup()
+
+	db, err := l5db.Open(td)
+	require.NoError(t, err)
+
+	wtx, err := db.NewWriteTransaction(context.Background())
+	require.NoError(t, err)
+
+	err = wtx.Put("abc", []byte{1, 2, 3})
+	require.NoError(t, err)
+
+	err = wtx.Commit()
+	require.NoError(t, err)
+
+	err = db.Close()
+	require.NoError(t, err)
+
+	db, err = l5db.Open(td)
+	require.NoError(t, err)
+	defer db.Close()
+
+	d, err := db.Get("abc")
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, d)
+
+}
+
+func TestWriteTransactionRollback(t *testing.T) {
+	db, cleanup := createEmptyDB(t)
+	defer cleanup()
+
+	wtx, err := db.NewWriteTransaction(context.Background())
+	require.NoError(t, err)
+
+	err = wtx.CreateMap("abc")
+	require.NoError(t, err)
+
+	err = wtx.Rollback()
+	require.NoError(t, err)
+
+	ex, err := db.Exists("abc")
+	require.NoError(t, err)
+	require.False(t, ex)
+
+	err = db.CreateMap("def")
+	require.NoError(t, err)
+
+	rootSize, err := db.Size("")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), rootSize)
+
+}
+
+func TestWriteTransactionAfterFinish(t *testing.T) {
+	db, cleanup := createEmptyDB(t)
+	defer cleanup()
+
+	err := db.Put("abc", []byte{1, 2, 3})
+	require.NoError(t, err)
+
+	committed, err := db.NewWriteTransaction(context.Background())
+	require.NoError(t, err)
+
+	err = committed.Commit()
+	require.NoError(t, err)
+
+	rolledBack, err := db.NewWriteTransaction(context.Background())
+	require.NoError(t, err)
+
+	err = rolledBack.Rollback()
+	require.NoError(t, err)
+
+	for _, tx := range []*l5db.WriteTransaction{committed, rolledBack} {
+		require.True(t, errors.Is(tx.CreateMap("m"), l5db.ErrTransactionFinished))
+		require.True(t, errors.Is(tx.Put("abc", []byte{4}), l5db.ErrTransactionFinished))
+		require.True(t, errors.Is(tx.Append("abc", []byte{4}), l5db.ErrTransactionFinished))
+		require.True(t, errors.Is(tx.Truncate("abc", 1), l5db.ErrTransactionFinished))
+		require.True(t, errors.Is(tx.WriteAt("abc", 0, []byte{4}), l5db.ErrTransactionFinished))
+		require.True(t, errors.Is(tx.Delete("abc"), l5db.ErrTransactionFinished))
+		require.True(t, errors.Is(tx.PutReader("abc", bytes.NewReader([]byte{4})), l5db.ErrTransactionFinished))
+
+		_, err = tx.Get("abc")
+		require.True(t, errors.Is(err, l5db.ErrTransactionFinished))
+
+		_, err = tx.GetReader("abc")
+		require.True(t, errors.Is(err, l5db.ErrTransactionFinished))
+
+		_, err = tx.Size("abc")
+		require.True(t, errors.Is(err, l5db.ErrTransactionFinished))
+
+		_, err = tx.Exists("abc")
+		require.True(t, errors.Is(err, l5db.ErrTransactionFinished))
+
+		_, err = tx.Iterator("")
+		require.True(t, errors.Is(err, l5db.ErrTransactionFinished))
+
+		_, err = tx.List("")
+		require.True(t, errors.Is(err, l5db.ErrTransactionFinished))
+
+		_, err = tx.Scan("", l5db.ScanOptions{})
+		require.True(t, errors.Is(err, l5db.ErrTransactionFinished))
+
+		_, err = tx.CreateWriter("abc")
+		require.True(t, errors.Is(err, l5db.ErrTransactionFinished))
+	}
+
+	d, err := db.Get("abc")
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, d)
+}
+
+func TestOnlyOneWriteTransactionAtATime(t *testing.T) {
+	db, cleanup := createEmptyDB(t)
+	defer cleanup()
+
+	wtx, err := db.NewWriteTransaction(context.Background())
+	require.NoError(t, err)
+
+	t.Run("second write transaction should time out", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err := db.NewWriteTransaction(ctx)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, context.DeadlineExceeded))
+	})
+
+	t.Run("direct put should wait for the write transaction", func(t *testing.T) {
+		putDone := make(chan error, 1)
+		go func() {
+			putDone <- db.Put("abc", []byte{1})
+		}()
+
+		select {
+		case <-putDone:
+			require.Fail(t, "put did not wait for the write transaction")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		err = wtx.Put("abc", []byte{2})
+		require.NoError(t, err)
+
+		err = wtx.Commit()
+		require.NoError(t, err)
+
+		require.NoError(t, <-putDone)
+
+		d, err := db.Get("abc")
+		require.NoError(t, err)
+		require.Equal(t, []byte{1}, d)
+	})
+
+	t.Run("finished transaction can't be committed again", func(t *testing.T) {
+		err = wtx.Commit()
+		require.Equal(t, l5db.ErrTransactionFinished, err)
+	})
+
+}
+
+func TestLastTxID(t *testing.T) {
+	td, cleanup := createTempDir(t)
+	defer cleanup()
+
+	db, err := l5db.Open(td)
+	require.NoError(t, err)
+
+	initial := db.LastTxID()
+
+	err = db.Put("abc", []byte{1})
+	require.NoError(t, err)
+	require.Equal(t, initial+1, db.LastTxID())
+
+	wtx, err := db.NewWriteTransaction(context.Background())
+	require.NoError(t, err)
+
+	err = wtx.Put("def", []byte{2})
+	require.NoError(t, err)
+
+	err = wtx.Rollback()
+	require.NoError(t, err)
+	require.Equal(t, initial+1, db.LastTxID())
+
+	wtx, err = db.NewWriteTransaction(context.Background())
+	require.NoError(t, err)
+
+	err = wtx.Put("def", []byte{2})
+	require.NoError(t, err)
+
+	err = wtx.Commit()
+	require.NoError(t, err)
+	require.Equal(t, initial+2, db.LastTxID())
+
+	err = db.Close()
+	require.NoError(t, err)
+
+	db, err = l5db.Open(td)
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.Equal(t, initial+2, db.LastTxID())
+}